@@ -2,27 +2,49 @@ package app
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"html/template"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"runtime/debug"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/gngtwhh/WBlog/internal/assets"
 	"github.com/gngtwhh/WBlog/internal/cache"
 	"github.com/gngtwhh/WBlog/internal/config"
 	"github.com/gngtwhh/WBlog/internal/handler"
+	"github.com/gngtwhh/WBlog/internal/metrics"
+	"github.com/gngtwhh/WBlog/internal/middleware"
 	"github.com/gngtwhh/WBlog/internal/render"
 	"github.com/gngtwhh/WBlog/internal/repository"
 	"github.com/gngtwhh/WBlog/internal/router"
 	"github.com/gngtwhh/WBlog/internal/service"
+	"github.com/gngtwhh/WBlog/pkg/i18n"
 	"github.com/gngtwhh/WBlog/pkg/logger"
+	"github.com/gngtwhh/WBlog/pkg/oauth"
+	"github.com/gngtwhh/WBlog/pkg/response"
 	"github.com/gngtwhh/WBlog/pkg/sensitive"
+	"github.com/gngtwhh/WBlog/pkg/storage"
+	"github.com/gngtwhh/WBlog/pkg/tracing"
 	"github.com/gngtwhh/WBlog/pkg/utils"
 )
 
 type Server struct {
 	server http.Server
 	logger *slog.Logger
+	// released in order after the http server has drained:
+	// background workers first, then database, then cache
+	closers []closer
+}
+
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
 }
 
 func NewServer() (h *Server) {
@@ -43,10 +65,25 @@ func NewServer() (h *Server) {
 
 	// utils
 	// jwt
-	if err := utils.InitJwt(config.Cfg.App.JwtSecret); err != nil {
+	jwtKeys, err := loadJwtKeys()
+	if err != nil {
+		log.Error("failed to load jwt keys", "err", err)
+		panic(err)
+	}
+	if err := utils.InitJwt(utils.JwtOptions{
+		Keys:      jwtKeys,
+		ActiveKey: config.Cfg.GetJwtActiveKey(),
+		Issuer:    config.Cfg.GetJwtIssuer(),
+		Audience:  config.Cfg.App.JwtAudience,
+	}); err != nil {
 		log.Error("failed to init jwt pkg", "err", err)
 		panic(err)
 	}
+	// message catalogs
+	if err := i18n.Load(config.Cfg.GetI18nDir(), config.Cfg.GetDefaultLocale()); err != nil {
+		log.Error("failed to load i18n catalogs", "err", err)
+		panic(err)
+	}
 	// sensitive words filter
 	file, err := os.Open(config.Cfg.App.SensitiveWordsFile)
 	if err != nil {
@@ -66,11 +103,26 @@ func NewServer() (h *Server) {
 	acFilter := sensitive.NewACFilter()
 	acFilter.Build(words)
 
-	// init redis cache
-	if err := cache.InitRedis(config.Cfg.Cache.RedisAddr, config.Cfg.Cache.RedisPassword); err != nil {
+	// tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), &tracing.Options{
+		ServiceName: config.Cfg.Tracing.ServiceName,
+		Version:     buildVersion(),
+		Exporter:    config.Cfg.Tracing.Exporter,
+		Endpoint:    config.Cfg.Tracing.Endpoint,
+		SampleRatio: config.Cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Error("failed to init tracing", "err", err)
+		panic(err)
+	}
+
+	// init cache
+	store, err := newCacheStore(log)
+	if err != nil {
 		log.Error("init cache failed", "err", err)
 		panic(err)
 	}
+	store = cache.NewTracedStore(store)
 
 	// init repository
 	log.Info("initializing database...")
@@ -79,54 +131,256 @@ func NewServer() (h *Server) {
 		log.Error("failed to connect database", "err", err)
 		panic(err)
 	}
-	articleRepo := repository.NewArticleRepo(db, log)
-	userRepo := repository.NewUserRepo(db, log)
-	commentRepo := repository.NewCommentRepo(db, log)
+	metrics.RegisterDB(db, "sqlite")
+	response.OnFail = metrics.ObserveErrCode
+	response.UseProblemJSON(config.Cfg.HTTP.ProblemJSON)
+	articleRepo := repository.TraceArticleRepo(repository.NewArticleRepo(db, log))
+	userRepo := repository.TraceUserRepo(repository.NewUserRepo(db, log))
+	commentRepo := repository.TraceCommentRepo(repository.NewCommentRepo(db, log))
+	identityRepo := repository.NewIdentityRepo(db, log)
+	mediaRepo := repository.NewMediaRepo(db, log)
+
+	// media storage
+	mediaStore, err := storage.NewLocal(config.Cfg.GetUploadDir(), config.Cfg.GetUploadURLPrefix())
+	if err != nil {
+		log.Error("failed to init media storage", "err", err)
+		panic(err)
+	}
+
+	// oauth providers
+	providers := make([]*oauth.Provider, 0, len(config.Cfg.OAuth.Providers))
+	for _, p := range config.Cfg.OAuth.Providers {
+		provider, err := oauth.NewProvider(context.Background(), oauth.Config{
+			Name:          p.Name,
+			ClientID:      p.ClientID,
+			ClientSecret:  p.ClientSecret,
+			RedirectURL:   p.RedirectURL,
+			Scopes:        p.Scopes,
+			Issuer:        p.Issuer,
+			AuthURL:       p.AuthURL,
+			TokenURL:      p.TokenURL,
+			UserInfoURL:   p.UserInfoURL,
+			SubjectClaim:  p.SubjectClaim,
+			UsernameClaim: p.UsernameClaim,
+			NicknameClaim: p.NicknameClaim,
+			AvatarClaim:   p.AvatarClaim,
+			EmailClaim:    p.EmailClaim,
+		}, nil)
+		if err != nil {
+			log.Error("failed to init oauth provider", "provider", p.Name, "err", err)
+			panic(err)
+		}
+		providers = append(providers, provider)
+	}
 
 	log.Info("initializing service...")
 	// init Services
-	articleService := service.NewArticleService(articleRepo, log)
-	userService := service.NewUserService(userRepo, log)
+	articleService := service.NewArticleService(articleRepo, store, log)
+	userService := service.NewUserService(userRepo, store, log)
 	commentService := service.NewCommentService(commentRepo, acFilter, log)
+	oauthService := service.NewOAuthService(providers, userRepo, identityRepo, log)
+	mediaService := service.NewMediaService(mediaRepo, mediaStore, log)
+	feedService := service.NewFeedService(articleService, store, log)
 
 	// init handler
 	app := &handler.App{
-		Index:   handler.NewIndexHandler(articleService),
-		Article: handler.NewArticleHandler(articleService),
-		User:    handler.NewUserHandler(userService),
-		Comment: handler.NewCommentHandler(commentService, articleService),
+		Index:     handler.NewIndexHandler(articleService),
+		Article:   handler.NewArticleHandler(articleService),
+		User:      handler.NewUserHandler(userService),
+		Comment:   handler.NewCommentHandler(commentService, articleService),
+		OAuth:     handler.NewOAuthHandler(oauthService),
+		WellKnown: handler.NewWellKnownHandler(),
+		Media:     handler.NewMediaHandler(mediaService),
+		Feed:      handler.NewFeedHandler(feedService),
+		Sitemap:   handler.NewSitemapHandler(articleService),
+		Health: handler.NewHealthHandler(
+			handler.HealthCheck{Name: "sqlite", Critical: true, Check: db.PingContext},
+			handler.HealthCheck{Name: "migrations", Critical: true, Check: func(ctx context.Context) error {
+				return repository.CheckSchema(ctx, db)
+			}},
+			handler.HealthCheck{Name: "templates", Critical: true, Check: func(context.Context) error {
+				if !render.Loaded() {
+					return errors.New("templates not loaded")
+				}
+				return nil
+			}},
+			handler.CacheCheck(store),
+		),
 	}
 
+	// cookie session & csrf
+	sameSite := map[string]http.SameSite{
+		"strict": http.SameSiteStrictMode,
+		"none":   http.SameSiteNoneMode,
+	}[config.Cfg.Session.SameSite]
+	middleware.InitSession(middleware.SessionOptions{
+		CookieAuth:     config.Cfg.Session.CookieAuth,
+		CookieName:     config.Cfg.Session.CookieName,
+		Secure:         config.Cfg.Session.CookieSecure,
+		SameSite:       sameSite,
+		CSRFCookieName: config.Cfg.Session.CSRFCookieName,
+		CSRFHeader:     config.Cfg.Session.CSRFHeader,
+	})
+
 	// html template pre-compile
 	log.Info("pre-compiling html templates...")
-	tmpls := loadTmlps()
+	manifest, err := assets.Load(config.Cfg.App.StaticDir, "/static/")
+	if err != nil {
+		log.Error("failed to fingerprint static files", "err", err)
+		panic(err)
+	}
+	tmpls := loadTmlps(manifest)
 	render.Init(tmpls, "layout")
 
 	h = &Server{
 		server: http.Server{
-			Addr:    ":" + config.Cfg.Server.Port,
-			Handler: router.LoadRouters(app, log),
+			Addr:              ":" + config.Cfg.Server.Port,
+			Handler:           router.LoadRouters(app, store, manifest, log),
+			ReadTimeout:       config.Cfg.GetReadTimeout(),
+			ReadHeaderTimeout: config.Cfg.GetReadHeaderTimeout(),
+			WriteTimeout:      config.Cfg.GetWriteTimeout(),
+			IdleTimeout:       config.Cfg.GetIdleTimeout(),
+			MaxHeaderBytes:    config.Cfg.GetMaxHeaderBytes(),
+			ErrorLog:          slog.NewLogLogger(log.Handler(), slog.LevelWarn),
 		},
 		logger: log,
 	}
+	h.onShutdown("tracing", shutdownTracing)
+	h.onShutdown("database", func(context.Context) error { return db.Close() })
+	h.onShutdown("cache", func(context.Context) error { return store.Close() })
 	return
 }
 
+// onShutdown registers a resource to release after the http server stops.
+func (s *Server) onShutdown(name string, fn func(ctx context.Context) error) {
+	s.closers = append(s.closers, closer{name: name, close: fn})
+}
+
+// Run serves until SIGINT/SIGTERM, then stops accepting connections, waits
+// for in-flight requests up to the shutdown timeout and releases resources.
 func (s *Server) Run() {
-	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		s.logger.Error("server startup failed", "err", err)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Info("server listening", "addr", s.server.Addr)
+		errCh <- s.server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil && err != http.ErrServerClosed {
+			s.logger.Error("server startup failed", "err", err)
+		}
+	case <-ctx.Done():
+		stop() // a second signal kills the process immediately
+		s.logger.Info("shutting down, draining in-flight requests", "timeout", config.Cfg.GetShutdownTimeout())
+	}
+	s.Shutdown()
+}
+
+// Shutdown drains the http server and closes registered resources in order.
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.GetShutdownTimeout())
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.logger.Warn("drain deadline exceeded, closing remaining connections", "err", err)
+		s.server.Close()
 	}
+	for _, c := range s.closers {
+		if err := c.close(ctx); err != nil {
+			s.logger.Error("failed to close "+c.name, "err", err)
+			continue
+		}
+		s.logger.Info("closed " + c.name)
+	}
+	s.logger.Info("server stopped")
 }
 
-func loadTmlps() map[string]*template.Template {
+func loadTmlps(manifest *assets.Manifest) map[string]*template.Template {
 	tmpls := make(map[string]*template.Template)
 
 	baseDir := config.Cfg.App.TemplateDir
 	layout := baseDir + "layout/layout.html"
+	funcs := template.FuncMap{
+		"asset": manifest.Path,
+		// feeds are only served with an absolute site url
+		"feeds": func() bool { return config.Cfg.GetBaseURL() != "" },
+	}
+	parse := func(page string) *template.Template {
+		return template.Must(template.New("layout.html").Funcs(funcs).ParseFiles(layout, baseDir+page))
+	}
 
-	tmpls["index"] = template.Must(template.ParseFiles(layout, baseDir+"index.html"))
-	tmpls["admin"] = template.Must(template.ParseFiles(layout, baseDir+"admin.html"))
-	tmpls["article"] = template.Must(template.ParseFiles(layout, baseDir+"article.html"))
+	tmpls["index"] = parse("index.html")
+	tmpls["admin"] = parse("admin.html")
+	tmpls["article"] = parse("article.html")
+	tmpls["404"] = parse("404.html")
 	// tmpls["layout"] = template.Must(template.ParseFiles("web/templates/layout.html"))
 	return tmpls
 }
+
+// newCacheStore selects the cache driver, small installs can run
+// without redis using the in-process LRU. Revoked tokens are pinned in
+// the LRU, evicting one would make a logged out token valid again.
+func newCacheStore(log *slog.Logger) (cache.Store, error) {
+	if config.Cfg.Cache.Driver == "memory" {
+		return cache.NewLRUStore(config.Cfg.Cache.LRUSize, cache.PrefixJWTBlacklist), nil
+	}
+	rdb, err := cache.NewRedisStore(config.Cfg.Cache.RedisAddr, config.Cfg.Cache.RedisPassword)
+	if err != nil {
+		return nil, err
+	}
+	openTimeout, _ := time.ParseDuration(config.Cfg.Cache.BreakerOpenTimeout) // checked by config.Load
+	return cache.NewBreakerStore(rdb, cache.NewLRUStore(config.Cfg.Cache.LRUSize, cache.PrefixJWTBlacklist), cache.BreakerOptions{
+		FailureThreshold: config.Cfg.Cache.BreakerThreshold,
+		OpenTimeout:      openTimeout,
+	}, log), nil
+}
+
+// loadJwtKeys builds the jwt key set from config, the legacy jwt_secret
+// becomes the HS256 key "default".
+func loadJwtKeys() ([]*utils.SigningKey, error) {
+	var keys []*utils.SigningKey
+	if config.Cfg.App.JwtSecret != "" {
+		k, err := utils.NewHMACKey(utils.DefaultKeyID, config.Cfg.App.JwtSecret)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	for _, kc := range config.Cfg.App.JwtKeys {
+		var k *utils.SigningKey
+		var err error
+		switch kc.Alg {
+		case utils.AlgHS256, "":
+			k, err = utils.NewHMACKey(kc.Kid, kc.Secret)
+		default:
+			var pemData []byte
+			pemData, err = os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			k, err = utils.NewPrivateKey(kc.Kid, kc.Alg, pemData)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if kc.RetiredAt != "" {
+			retiredAt, _ := time.Parse(time.RFC3339, kc.RetiredAt) // checked by config.Load
+			k.VerifyUntil = retiredAt.Add(config.Cfg.GetJwtGracePeriod())
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// buildVersion returns the main module version embedded by go build.
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Version
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// immutable is sent for fingerprinted files, their name changes with content.
+const immutable = "public, max-age=31536000, immutable"
+
+// Manifest maps static files to fingerprinted names,
+// e.g. "css/style.css" -> "css/style.3f2a9c1d.css".
+type Manifest struct {
+	prefix  string
+	dir     string
+	byName  map[string]string // logical -> fingerprinted
+	byPrint map[string]string // fingerprinted -> logical
+}
+
+// Load hashes every file below dir, urlPrefix is where the files are served.
+func Load(dir, urlPrefix string) (*Manifest, error) {
+	m := &Manifest{
+		prefix:  urlPrefix,
+		dir:     dir,
+		byName:  make(map[string]string),
+		byPrint: make(map[string]string),
+	}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		ext := path.Ext(name)
+		printed := strings.TrimSuffix(name, ext) + "." + sum[:8] + ext
+		m.byName[name] = printed
+		m.byPrint[printed] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Path returns the public URL of a static file, fingerprinted if known.
+// Used in templates as {{asset "css/style.css"}}.
+func (m *Manifest) Path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if printed, ok := m.byName[name]; ok {
+		return m.prefix + printed
+	}
+	return m.prefix + name
+}
+
+// Handler serves dir, the prefix must already be stripped. Fingerprinted
+// names are cached forever, plain names must be revalidated.
+func (m *Manifest) Handler() http.Handler {
+	files := http.FileServer(http.Dir(m.dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if logical, ok := m.byPrint[name]; ok {
+			w.Header().Set("Cache-Control", immutable)
+			u := *r.URL
+			u.Path = "/" + logical
+			r2 := r.Clone(r.Context())
+			r2.URL = &u
+			files.ServeHTTP(w, r2)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,43 @@
+package assets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "css"), 0755)
+	os.WriteFile(filepath.Join(dir, "css", "style.css"), []byte("body{}"), 0644)
+
+	m, err := Load(dir, "/static/")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	printed := m.Path("css/style.css")
+	if !strings.HasPrefix(printed, "/static/css/style.") || !strings.HasSuffix(printed, ".css") || printed == "/static/css/style.css" {
+		t.Fatalf("Path = %q, want fingerprinted name", printed)
+	}
+	if got := m.Path("js/missing.js"); got != "/static/js/missing.js" {
+		t.Errorf("Path of unknown file = %q", got)
+	}
+
+	h := http.StripPrefix("/static/", m.Handler())
+	for path, wantCC := range map[string]string{
+		printed:                 "public, max-age=31536000, immutable",
+		"/static/css/style.css": "no-cache",
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != "body{}" {
+			t.Errorf("GET %s: code = %d, body = %q", path, rec.Code, rec.Body.String())
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != wantCC {
+			t.Errorf("GET %s: Cache-Control = %q, want %q", path, cc, wantCC)
+		}
+	}
+}
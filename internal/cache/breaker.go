@@ -0,0 +1,268 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// breaker states
+const (
+	StateClosed   = "closed"    // primary store in use
+	StateOpen     = "open"      // primary unhealthy, fallback in use
+	StateHalfOpen = "half_open" // one probe request is trying the primary
+)
+
+// maxPendingDels bounds the keys remembered while open.
+const maxPendingDels = 10000
+
+type BreakerOptions struct {
+	FailureThreshold int           // consecutive failures before opening, default 5
+	OpenTimeout      time.Duration // wait before probing the primary again, default 10s
+}
+
+// Health is a snapshot of the breaker.
+type Health struct {
+	State     string    `json:"state"`
+	Failures  int       `json:"failures"`
+	OpenedAt  time.Time `json:"opened_at,omitzero"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// BreakerStore wraps a remote Store with a circuit breaker. While the
+// primary is unhealthy all calls are served by a local fallback, keys
+// deleted in that time are deleted from the primary again before it is
+// used, so it does not serve stale data.
+type BreakerStore struct {
+	primary  Store
+	fallback Store
+	opts     BreakerOptions
+	log      *slog.Logger
+
+	mu          sync.Mutex
+	state       string
+	failures    int
+	openedAt    time.Time
+	lastErr     error
+	pendingDels map[string]struct{}
+}
+
+func NewBreakerStore(primary, fallback Store, opts BreakerOptions, logger *slog.Logger) *BreakerStore {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = 10 * time.Second
+	}
+	return &BreakerStore{
+		primary:     primary,
+		fallback:    fallback,
+		opts:        opts,
+		log:         logger.With("component", "cache_breaker"),
+		state:       StateClosed,
+		pendingDels: make(map[string]struct{}),
+	}
+}
+
+// acquire decides whether a call may go to the primary. The first call
+// after the open timeout is a probe, pending deletes are replayed before
+// it so the primary never serves keys deleted during the outage.
+func (s *BreakerStore) acquire(ctx context.Context) bool {
+	s.mu.Lock()
+	switch {
+	case s.state == StateClosed:
+		s.mu.Unlock()
+		return true
+	case s.state == StateHalfOpen || time.Since(s.openedAt) < s.opts.OpenTimeout:
+		s.mu.Unlock()
+		return false
+	}
+	s.state = StateHalfOpen
+	dels := make([]string, 0, len(s.pendingDels))
+	for k := range s.pendingDels {
+		dels = append(dels, k)
+	}
+	s.mu.Unlock()
+
+	if len(dels) == 0 {
+		return true
+	}
+	if err := s.primary.Del(ctx, dels...); err != nil {
+		s.onFailure(err)
+		return false
+	}
+	s.mu.Lock()
+	for _, k := range dels {
+		delete(s.pendingDels, k)
+	}
+	s.mu.Unlock()
+	return true
+}
+
+// release records the result of a primary call. ErrMiss is a healthy answer.
+func (s *BreakerStore) release(err error) {
+	if err != nil && !errors.Is(err, ErrMiss) {
+		s.onFailure(err)
+		return
+	}
+	s.mu.Lock()
+	recovered := s.state != StateClosed
+	s.state = StateClosed
+	s.failures = 0
+	s.mu.Unlock()
+	if recovered {
+		s.log.Info("cache recovered, switched back to primary")
+	}
+}
+
+func (s *BreakerStore) onFailure(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+	s.failures++
+	if s.state == StateHalfOpen || (s.state == StateClosed && s.failures >= s.opts.FailureThreshold) {
+		if s.state == StateClosed {
+			s.log.Warn("cache unhealthy, switched to local fallback", "failures", s.failures, "err", err)
+		}
+		s.state = StateOpen
+		s.openedAt = time.Now()
+	}
+}
+
+// Health reports the breaker state.
+func (s *BreakerStore) Health() Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := Health{State: s.state, Failures: s.failures}
+	if s.state != StateClosed {
+		h.OpenedAt = s.openedAt
+	}
+	if s.lastErr != nil {
+		h.LastError = s.lastErr.Error()
+	}
+	return h
+}
+
+// Degraded reports whether calls are served by the fallback.
+func (s *BreakerStore) Degraded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state != StateClosed
+}
+
+func (s *BreakerStore) Get(ctx context.Context, key string) (string, error) {
+	if !s.acquire(ctx) {
+		return s.fallback.Get(ctx, key)
+	}
+	val, err := s.primary.Get(ctx, key)
+	s.release(err)
+	if err != nil && !errors.Is(err, ErrMiss) {
+		return s.fallback.Get(ctx, key)
+	}
+	return val, err
+}
+
+func (s *BreakerStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if !s.acquire(ctx) {
+		return s.fallback.Set(ctx, key, value, ttl)
+	}
+	err := s.primary.Set(ctx, key, value, ttl)
+	s.release(err)
+	if err != nil {
+		return s.fallback.Set(ctx, key, value, ttl)
+	}
+	return nil
+}
+
+func (s *BreakerStore) Del(ctx context.Context, keys ...string) error {
+	// always clear the fallback, it may hold values written while open
+	s.fallback.Del(ctx, keys...)
+	if s.acquire(ctx) {
+		err := s.primary.Del(ctx, keys...)
+		s.release(err)
+		if err == nil {
+			return nil
+		}
+	}
+	s.mu.Lock()
+	for _, k := range keys {
+		if len(s.pendingDels) < maxPendingDels {
+			s.pendingDels[k] = struct{}{}
+		}
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// Exists also checks the fallback, keys set during an outage (e.g. revoked
+// tokens) only live there. A failing primary is reported even while the
+// breaker is closed, callers such as the auth blacklist decide what a
+// missing answer means.
+func (s *BreakerStore) Exists(ctx context.Context, key string) (bool, error) {
+	fbOK, fbErr := s.fallback.Exists(ctx, key)
+	if fbOK {
+		return true, nil
+	}
+	if !s.acquire(ctx) {
+		return false, fbErr
+	}
+	ok, err := s.primary.Exists(ctx, key)
+	s.release(err)
+	if err != nil {
+		return false, errors.Join(err, fbErr)
+	}
+	return ok, nil
+}
+
+func (s *BreakerStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if !s.acquire(ctx) {
+		return s.fallback.Incr(ctx, key, ttl)
+	}
+	n, err := s.primary.Incr(ctx, key, ttl)
+	s.release(err)
+	if err != nil {
+		return s.fallback.Incr(ctx, key, ttl)
+	}
+	return n, nil
+}
+
+// Ping checks the primary directly, regardless of the breaker state.
+func (s *BreakerStore) Ping(ctx context.Context) error {
+	return Ping(ctx, s.primary)
+}
+
+func (s *BreakerStore) Close() error {
+	s.fallback.Close()
+	return s.primary.Close()
+}
+
+// Degraded reports whether store is running on a local fallback,
+// stores without a breaker are never degraded.
+func Degraded(store Store) bool {
+	d, ok := store.(interface{ Degraded() bool })
+	return ok && d.Degraded()
+}
+
+// HealthOf returns the breaker health of store, ok is false for stores
+// without a breaker.
+func HealthOf(store Store) (Health, bool) {
+	switch s := store.(type) {
+	case *BreakerStore:
+		return s.Health(), true
+	case interface{ Health() (Health, bool) }:
+		return s.Health()
+	}
+	return Health{}, false
+}
+
+// Ping checks the backend of store is reachable, in-process stores
+// always are.
+func Ping(ctx context.Context, store Store) error {
+	p, ok := store.(interface{ Ping(context.Context) error })
+	if !ok {
+		return nil
+	}
+	return p.Ping(ctx)
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// flakyStore is an LRUStore that fails every call while down is set.
+type flakyStore struct {
+	*LRUStore
+	down bool
+}
+
+var errDown = errors.New("connection refused")
+
+func (s *flakyStore) Get(ctx context.Context, key string) (string, error) {
+	if s.down {
+		return "", errDown
+	}
+	return s.LRUStore.Get(ctx, key)
+}
+
+func (s *flakyStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if s.down {
+		return errDown
+	}
+	return s.LRUStore.Set(ctx, key, value, ttl)
+}
+
+func (s *flakyStore) Del(ctx context.Context, keys ...string) error {
+	if s.down {
+		return errDown
+	}
+	return s.LRUStore.Del(ctx, keys...)
+}
+
+func (s *flakyStore) Exists(ctx context.Context, key string) (bool, error) {
+	if s.down {
+		return false, errDown
+	}
+	return s.LRUStore.Exists(ctx, key)
+}
+
+func TestBreakerStore(t *testing.T) {
+	ctx := context.Background()
+	primary := &flakyStore{LRUStore: NewLRUStore(100)}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewBreakerStore(primary, NewLRUStore(100), BreakerOptions{
+		FailureThreshold: 2,
+		OpenTimeout:      20 * time.Millisecond,
+	}, logger)
+
+	s.Set(ctx, "article:1", "old", 0)
+
+	primary.down = true
+	for i := 0; i < 2; i++ {
+		if _, err := s.Get(ctx, "article:1"); !errors.Is(err, ErrMiss) {
+			t.Fatalf("Get while failing: err = %v, want fallback miss", err)
+		}
+	}
+	if h := s.Health(); h.State != StateOpen || !s.Degraded() {
+		t.Fatalf("state = %s, want %s", h.State, StateOpen)
+	}
+
+	// writes while open go to the fallback
+	s.Set(ctx, "jwt:blacklist:t", "1", time.Minute)
+	s.Del(ctx, "article:1")
+	if ok, _ := s.Exists(ctx, "jwt:blacklist:t"); !ok {
+		t.Error("revoked token should be visible while open")
+	}
+
+	// recovery after the open timeout, the delete is replayed
+	primary.down = false
+	time.Sleep(30 * time.Millisecond)
+	if _, err := s.Get(ctx, "article:1"); !errors.Is(err, ErrMiss) {
+		t.Errorf("stale key should be deleted on recovery, err = %v", err)
+	}
+	if h := s.Health(); h.State != StateClosed {
+		t.Errorf("state = %s, want %s", h.State, StateClosed)
+	}
+	if ok, _ := s.Exists(ctx, "jwt:blacklist:t"); !ok {
+		t.Error("token revoked during outage should stay revoked")
+	}
+}
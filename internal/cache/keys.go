@@ -4,4 +4,26 @@ const (
 	// Key: jwt:blacklist:{token_string}
 	// Value: "1"
 	PrefixJWTBlacklist = "jwt:blacklist:"
+
+	// Key: article:detail:{id}
+	// Value: json of model.Article, or NotFoundMarker
+	PrefixArticleDetail = "article:detail:"
+
+	// Key: article:list:version
+	// Value: unix nano, renewed on every article change. List and count keys
+	// embed it, so a bump invalidates all of them at once.
+	KeyArticleListVersion = "article:list:version"
+	// Key: article:list:v{version}:{query key}
+	// Value: json of []model.Article
+	PrefixArticleList = "article:list:"
+	// Key: article:count:v{version}:{filter key}
+	// Value: integer
+	PrefixArticleCount = "article:count:"
+
+	// Key: feed:v{version}:{format}
+	// Value: json of the generated feed and its update time
+	PrefixFeed = "feed:"
 )
+
+// NotFoundMarker is cached for missing records (negative caching).
+const NotFoundMarker = "\x00nil"
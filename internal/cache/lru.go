@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LRUStore is an in-process Store, the least recently used key is
+// evicted once capacity is reached. Expired keys are removed lazily.
+// Keys under a pinned prefix are never evicted, only expired, so a burst
+// of other keys can not push out e.g. revoked tokens.
+type LRUStore struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+
+	prefixes []string
+	pinned   map[string]*lruEntry
+	sweepAt  int // size of pinned that triggers a sweep of expired keys
+}
+
+type lruEntry struct {
+	key      string
+	value    string
+	expireAt time.Time // zero means never
+}
+
+// minSweep is the smallest pinned size that is swept for expired keys.
+const minSweep = 1024
+
+// NewLRUStore returns a store of capacity keys, keys starting with one of
+// the pinned prefixes do not count against it and are never evicted.
+func NewLRUStore(capacity int, pinned ...string) *LRUStore {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &LRUStore{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		prefixes: pinned,
+		pinned:   make(map[string]*lruEntry),
+		sweepAt:  minSweep,
+	}
+}
+
+func (s *LRUStore) isPinned(key string) bool {
+	for _, p := range s.prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *lruEntry) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && now.After(e.expireAt)
+}
+
+// get returns the live entry of key, caller must hold mu.
+func (s *LRUStore) get(key string) (*lruEntry, bool) {
+	if s.isPinned(key) {
+		e, ok := s.pinned[key]
+		if ok && e.expired(time.Now()) {
+			delete(s.pinned, key)
+			return nil, false
+		}
+		return e, ok
+	}
+	el, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if e.expired(time.Now()) {
+		s.ll.Remove(el)
+		delete(s.items, key)
+		return nil, false
+	}
+	s.ll.MoveToFront(el)
+	return e, true
+}
+
+// set stores value, caller must hold mu.
+func (s *LRUStore) set(key, value string, ttl time.Duration) {
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+	if s.isPinned(key) {
+		s.pinned[key] = &lruEntry{key: key, value: value, expireAt: expireAt}
+		if len(s.pinned) >= s.sweepAt {
+			s.sweep()
+		}
+		return
+	}
+	if el, ok := s.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.value, e.expireAt = value, expireAt
+		s.ll.MoveToFront(el)
+		return
+	}
+	s.items[key] = s.ll.PushFront(&lruEntry{key: key, value: value, expireAt: expireAt})
+	for s.ll.Len() > s.capacity {
+		oldest := s.ll.Back()
+		s.ll.Remove(oldest)
+		delete(s.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// sweep removes expired pinned keys, nobody may ask for them again.
+// Caller must hold mu.
+func (s *LRUStore) sweep() {
+	now := time.Now()
+	for key, e := range s.pinned {
+		if e.expired(now) {
+			delete(s.pinned, key)
+		}
+	}
+	s.sweepAt = max(2*len(s.pinned), minSweep)
+}
+
+func (s *LRUStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.get(key)
+	if !ok {
+		return "", ErrMiss
+	}
+	return e.value, nil
+}
+
+func (s *LRUStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(key, value, ttl)
+	return nil
+}
+
+func (s *LRUStore) Del(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.pinned, key)
+		if el, ok := s.items[key]; ok {
+			s.ll.Remove(el)
+			delete(s.items, key)
+		}
+	}
+	return nil
+}
+
+func (s *LRUStore) Exists(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.get(key)
+	return ok, nil
+}
+
+func (s *LRUStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.get(key)
+	if !ok {
+		s.set(key, "1", ttl)
+		return 1, nil
+	}
+	n, err := strconv.ParseInt(e.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	n++
+	e.value = strconv.FormatInt(n, 10) // keeps the original expiry, like redis
+	return n, nil
+}
+
+func (s *LRUStore) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLRUStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewLRUStore(2)
+
+	s.Set(ctx, "a", "1", 0)
+	s.Set(ctx, "b", "2", 0)
+	s.Get(ctx, "a") // a is now most recently used
+	s.Set(ctx, "c", "3", 0)
+	if _, err := s.Get(ctx, "b"); !errors.Is(err, ErrMiss) {
+		t.Errorf("b should be evicted, err = %v", err)
+	}
+	if v, err := s.Get(ctx, "a"); err != nil || v != "1" {
+		t.Errorf("Get(a) = %q, %v", v, err)
+	}
+
+	s.Set(ctx, "ttl", "x", 10*time.Millisecond)
+	if ok, _ := s.Exists(ctx, "ttl"); !ok {
+		t.Error("ttl key should exist")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if ok, _ := s.Exists(ctx, "ttl"); ok {
+		t.Error("ttl key should be expired")
+	}
+
+	for want := int64(1); want <= 3; want++ {
+		if n, err := s.Incr(ctx, "counter", time.Minute); err != nil || n != want {
+			t.Errorf("Incr = %d, %v, want %d", n, err, want)
+		}
+	}
+	s.Del(ctx, "counter")
+	if ok, _ := s.Exists(ctx, "counter"); ok {
+		t.Error("counter should be deleted")
+	}
+}
+
+func TestLRUStorePinned(t *testing.T) {
+	ctx := context.Background()
+	s := NewLRUStore(2, "jwt:blacklist:")
+
+	s.Set(ctx, "jwt:blacklist:t", "1", time.Minute)
+	for i := 0; i < 10; i++ {
+		s.Set(ctx, "article:list:"+string(rune('a'+i)), "x", 0)
+	}
+	if ok, _ := s.Exists(ctx, "jwt:blacklist:t"); !ok {
+		t.Error("pinned key should survive eviction")
+	}
+	s.Del(ctx, "jwt:blacklist:t")
+	if ok, _ := s.Exists(ctx, "jwt:blacklist:t"); ok {
+		t.Error("pinned key should be deleted")
+	}
+
+	s.Set(ctx, "jwt:blacklist:ttl", "1", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if ok, _ := s.Exists(ctx, "jwt:blacklist:ttl"); ok {
+		t.Error("pinned key should still expire")
+	}
+}
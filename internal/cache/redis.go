@@ -2,16 +2,20 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-var RDB *redis.Client
+// RedisStore implements Store on top of redis.
+type RedisStore struct {
+	rdb *redis.Client
+}
 
-func InitRedis(addr, password string) error {
-	RDB = redis.NewClient(&redis.Options{
+func NewRedisStore(addr, password string) (*RedisStore, error) {
+	rdb := redis.NewClient(&redis.Options{
 		Addr:     addr,
 		Password: password,
 		DB:       0,
@@ -19,8 +23,57 @@ func InitRedis(addr, password string) error {
 	})
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
-	if _, err := RDB.Ping(ctx).Result(); err != nil {
-		return fmt.Errorf("connect to redis failed: %w", err)
+	if _, err := rdb.Ping(ctx).Result(); err != nil {
+		rdb.Close()
+		return nil, fmt.Errorf("connect to redis failed: %w", err)
+	}
+	return &RedisStore{rdb: rdb}, nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	val, err := s.rdb.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrMiss
+	}
+	return val, err
+}
+
+func (s *RedisStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return s.rdb.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *RedisStore) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
 	}
-	return nil
+	return s.rdb.Del(ctx, keys...).Err()
+}
+
+func (s *RedisStore) Exists(ctx context.Context, key string) (bool, error) {
+	n, err := s.rdb.Exists(ctx, key).Result()
+	return n > 0, err
+}
+
+func (s *RedisStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	n, err := s.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 && ttl > 0 {
+		if err := s.rdb.Expire(ctx, key, ttl).Err(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.rdb.Ping(ctx).Err()
+}
+
+func (s *RedisStore) Close() error {
+	return s.rdb.Close()
 }
@@ -0,0 +1,22 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned by Store.Get when the key does not exist.
+var ErrMiss = errors.New("cache: miss")
+
+// Store is a key-value cache with per-key TTL.
+// A ttl <= 0 means the key never expires.
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	// Incr increments the integer at key, a new key starts from 0 and gets ttl.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	Close() error
+}
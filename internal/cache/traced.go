@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gngtwhh/WBlog/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracedStore records every call of the wrapped store as a span. Degraded,
+// Health and Ping are forwarded, so the wrapper is transparent to callers.
+type TracedStore struct {
+	next Store
+}
+
+func NewTracedStore(next Store) *TracedStore {
+	return &TracedStore{next: next}
+}
+
+func (s *TracedStore) start(ctx context.Context, op, key string) (context.Context, trace.Span) {
+	// blacklist keys embed the token itself
+	if strings.HasPrefix(key, PrefixJWTBlacklist) {
+		key = PrefixJWTBlacklist + "*"
+	}
+	return tracing.Start(ctx, "cache."+op, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("cache.key", key)))
+}
+
+func (s *TracedStore) Get(ctx context.Context, key string) (string, error) {
+	ctx, span := s.start(ctx, "Get", key)
+	val, err := s.next.Get(ctx, key)
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+	tracing.End(span, err, ErrMiss)
+	return val, err
+}
+
+func (s *TracedStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	ctx, span := s.start(ctx, "Set", key)
+	err := s.next.Set(ctx, key, value, ttl)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *TracedStore) Del(ctx context.Context, keys ...string) error {
+	key := ""
+	if len(keys) > 0 {
+		key = keys[0]
+	}
+	ctx, span := s.start(ctx, "Del", key)
+	err := s.next.Del(ctx, keys...)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *TracedStore) Exists(ctx context.Context, key string) (bool, error) {
+	ctx, span := s.start(ctx, "Exists", key)
+	ok, err := s.next.Exists(ctx, key)
+	tracing.End(span, err)
+	return ok, err
+}
+
+func (s *TracedStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	ctx, span := s.start(ctx, "Incr", key)
+	n, err := s.next.Incr(ctx, key, ttl)
+	tracing.End(span, err)
+	return n, err
+}
+
+func (s *TracedStore) Degraded() bool {
+	return Degraded(s.next)
+}
+
+func (s *TracedStore) Health() (Health, bool) {
+	return HealthOf(s.next)
+}
+
+func (s *TracedStore) Ping(ctx context.Context) error {
+	return Ping(ctx, s.next)
+}
+
+func (s *TracedStore) Close() error {
+	return s.next.Close()
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -15,13 +18,75 @@ type Config struct {
 	Database DatabaseConfig `json:"database"`
 	App      AppConfig      `json:"app"`
 	Cache    CacheConfig    `json:"cache"`
+	OAuth    OAuthConfig    `json:"oauth"`
+	Session  SessionConfig  `json:"session"`
+	Upload   UploadConfig   `json:"upload"`
+	HTTP     HTTPConfig     `json:"http"`
+	Metrics  MetricsConfig  `json:"metrics"`
+	Tracing  TracingConfig  `json:"tracing"`
+	Site     SiteConfig     `json:"site"`
+	Feed     FeedConfig     `json:"feed"`
+}
+
+// SiteConfig describes the blog to feeds and crawlers.
+type SiteConfig struct {
+	Title       string `json:"title"` // default "WBlog"
+	Description string `json:"description"`
+	// absolute URL of the site, e.g. "https://blog.example.com". Feeds,
+	// sitemaps and canonical links need it and are left out when empty,
+	// the Host header of requests is not trusted for them
+	BaseURL string `json:"base_url"`
+	// URLs per sitemap, larger sites get a sitemap index; default and
+	// at most 50000
+	SitemapSize int `json:"sitemap_size"`
+	// path prefixes of /robots.txt, disallow defaults to ["/admin", "/api/"]
+	RobotsDisallow []string `json:"robots_disallow"`
+	RobotsAllow    []string `json:"robots_allow"`
+}
+
+// FeedConfig controls the RSS, Atom and JSON feeds.
+type FeedConfig struct {
+	Size        int  `json:"size"`         // newest articles per feed, default 20
+	FullContent bool `json:"full_content"` // publish content, not only abstracts
+}
+
+type TracingConfig struct {
+	// "otlp", "stdout", or empty to disable exporting
+	Exporter    string  `json:"exporter"`
+	Endpoint    string  `json:"endpoint"` // OTLP/HTTP traces URL
+	ServiceName string  `json:"service_name"`
+	SampleRatio float64 `json:"sample_ratio"` // 0 means 1
+}
+
+type MetricsConfig struct {
+	// bearer token required by /metrics, empty leaves it open
+	Token string `json:"token"`
+}
+
+type HTTPConfig struct {
+	// route path -> Cache-Control of cacheable GET routes, e.g.
+	// {"/api/get-article": "public, max-age=60"}. Default is "no-cache",
+	// clients may store but must revalidate with ETag.
+	CacheControl map[string]string `json:"cache_control"`
+	// Response compression, negotiated from Accept-Encoding. Bodies
+	// smaller than CompressMinSize bytes are sent as is.
+	DisableCompression bool `json:"disable_compression"`
+	CompressMinSize    int  `json:"compress_min_size"`
+	// reply errors as RFC 7807 application/problem+json instead of the
+	// {code, msg, data} envelope
+	ProblemJSON bool `json:"problem_json"`
 }
 
 type ServerConfig struct {
-	Port    string `json:"port"`
-	RunMode string `json:"run_mode"`
-	// ReadTimeout  int    `json:"read_timeout"`  // second
-	// WriteTimeout int    `json:"write_timeout"` // second
+	Port              string `json:"port"`
+	RunMode           string `json:"run_mode"`
+	ReadTimeout       int    `json:"read_timeout"`        // second
+	ReadHeaderTimeout int    `json:"read_header_timeout"` // second
+	WriteTimeout      int    `json:"write_timeout"`       // second
+	IdleTimeout       int    `json:"idle_timeout"`        // second
+	ShutdownTimeout   int    `json:"shutdown_timeout"`    // second, drain deadline
+	MaxHeaderBytes    int    `json:"max_header_bytes"`
+	MaxBodyBytes      int64  `json:"max_body_bytes"` // non-upload request bodies
 }
 
 type DatabaseConfig struct {
@@ -34,14 +99,84 @@ type AppConfig struct {
 	TemplateDir        string `json:"template_dir"`
 	StaticDir          string `json:"static_dir"`
 	LogFile            string `json:"log_file"`
-	JwtSecret          string `json:"jwt_secret"`
+	JwtSecret          string `json:"jwt_secret"` // legacy HS256 key, kid "default"
 	JwtExpireTime      string `json:"jwt_expire_time"`
 	SensitiveWordsFile string `json:"sensitive_words_file"`
+	I18nDir            string `json:"i18n_dir"`       // message catalogs, one {locale}.json each
+	DefaultLocale      string `json:"default_locale"` // used when Accept-Language matches none
+
+	JwtKeys        []JwtKeyConfig `json:"jwt_keys"`
+	JwtActiveKey   string         `json:"jwt_active_key"`   // kid used for signing, default "default"
+	JwtGracePeriod string         `json:"jwt_grace_period"` // how long retired keys still verify
+	JwtIssuer      string         `json:"jwt_issuer"`
+	JwtAudience    []string       `json:"jwt_audience"`
+}
+
+// JwtKeyConfig is one entry of the JWT key set.
+type JwtKeyConfig struct {
+	Kid            string `json:"kid"`
+	Alg            string `json:"alg"`              // HS256, RS256 or EdDSA
+	Secret         string `json:"secret"`           // HS256 only
+	PrivateKeyFile string `json:"private_key_file"` // PEM, RS256/EdDSA only
+	RetiredAt      string `json:"retired_at"`       // RFC3339, empty means in use
 }
 
 type CacheConfig struct {
+	Driver        string `json:"driver"` // redis(default) or memory
 	RedisAddr     string `json:"redis_addr"`
 	RedisPassword string `json:"redis_password"`
+	LRUSize       int    `json:"lru_size"` // max keys of the memory driver, default 10000
+
+	// circuit breaker around redis, a local LRU serves while it is open
+	BreakerThreshold   int    `json:"breaker_threshold"`    // consecutive failures, default 5
+	BreakerOpenTimeout string `json:"breaker_open_timeout"` // e.g. "10s"
+	// reject authenticated requests while revoked tokens can not be checked
+	BlacklistFailClosed bool `json:"blacklist_fail_closed"`
+}
+
+type UploadConfig struct {
+	Dir           string `json:"dir"`             // default "./data/uploads/"
+	URLPrefix     string `json:"url_prefix"`      // default "/uploads/"
+	MaxAvatarSize int64  `json:"max_avatar_size"` // bytes, default 2MB
+	MaxMediaSize  int64  `json:"max_media_size"`  // bytes, default 10MB
+	AvatarSizes   []int  `json:"avatar_sizes"`    // square sizes in px, default [256, 128, 64]
+	DefaultAvatar string `json:"default_avatar"`  // default "/static/default_avatar.png"
+}
+
+// SessionConfig controls cookie based authentication.
+type SessionConfig struct {
+	CookieAuth     bool   `json:"cookie_auth"`      // allow login to set the token cookie
+	CookieName     string `json:"cookie_name"`      // default "token"
+	CookieSecure   bool   `json:"cookie_secure"`    // send cookies over https only
+	SameSite       string `json:"same_site"`        // lax(default), strict or none
+	CSRFCookieName string `json:"csrf_cookie_name"` // default "csrf_token"
+	CSRFHeader     string `json:"csrf_header"`      // default "X-CSRF-Token"
+}
+
+type OAuthConfig struct {
+	Providers []OAuthProviderConfig `json:"providers"`
+}
+
+// OAuthProviderConfig configures one OAuth2/OIDC login provider.
+// Endpoints can be omitted if issuer supports OIDC discovery.
+type OAuthProviderConfig struct {
+	Name         string   `json:"name"` // used in /api/oauth/{name}/...
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+
+	Issuer      string `json:"issuer"`
+	AuthURL     string `json:"auth_url"`
+	TokenURL    string `json:"token_url"`
+	UserInfoURL string `json:"userinfo_url"`
+
+	// userinfo claim mapping, empty means OIDC standard claims
+	SubjectClaim  string `json:"subject_claim"`
+	UsernameClaim string `json:"username_claim"`
+	NicknameClaim string `json:"nickname_claim"`
+	AvatarClaim   string `json:"avatar_claim"`
+	EmailClaim    string `json:"email_claim"`
 }
 
 func (cfg *Config) GetJwtDuration() time.Duration {
@@ -52,6 +187,176 @@ func (cfg *Config) GetJwtDuration() time.Duration {
 	return d
 }
 
+// GetJwtGracePeriod returns how long a retired key keeps verifying tokens,
+// defaults to the token lifetime so no issued token is cut short.
+func (cfg *Config) GetJwtGracePeriod() time.Duration {
+	d, err := time.ParseDuration(cfg.App.JwtGracePeriod)
+	if err != nil {
+		return cfg.GetJwtDuration()
+	}
+	return d
+}
+
+func (cfg *Config) GetJwtIssuer() string {
+	if cfg.App.JwtIssuer == "" {
+		return "WBLOG"
+	}
+	return cfg.App.JwtIssuer
+}
+
+func (cfg *Config) GetJwtActiveKey() string {
+	if cfg.App.JwtActiveKey == "" {
+		return "default"
+	}
+	return cfg.App.JwtActiveKey
+}
+
+func (cfg *Config) GetI18nDir() string {
+	if cfg.App.I18nDir == "" {
+		return "./configs/i18n/"
+	}
+	return cfg.App.I18nDir
+}
+
+func (cfg *Config) GetDefaultLocale() string {
+	if cfg.App.DefaultLocale == "" {
+		return "zh-CN"
+	}
+	return cfg.App.DefaultLocale
+}
+
+// GetBaseURL returns the site URL without trailing slash, empty if unset.
+func (cfg *Config) GetBaseURL() string {
+	return strings.TrimSuffix(cfg.Site.BaseURL, "/")
+}
+
+func (cfg *Config) GetSiteTitle() string {
+	if cfg.Site.Title == "" {
+		return "WBlog"
+	}
+	return cfg.Site.Title
+}
+
+func (cfg *Config) GetSitemapSize() int {
+	if cfg.Site.SitemapSize <= 0 || cfg.Site.SitemapSize > 50000 {
+		return 50000
+	}
+	return cfg.Site.SitemapSize
+}
+
+func (cfg *Config) GetRobotsDisallow() []string {
+	if cfg.Site.RobotsDisallow == nil {
+		return []string{"/admin", "/api/"}
+	}
+	return cfg.Site.RobotsDisallow
+}
+
+func (cfg *Config) GetFeedSize() int {
+	if cfg.Feed.Size <= 0 {
+		return 20
+	}
+	return cfg.Feed.Size
+}
+
+func seconds(v, def int) time.Duration {
+	if v <= 0 {
+		v = def
+	}
+	return time.Duration(v) * time.Second
+}
+
+func (cfg *Config) GetReadTimeout() time.Duration {
+	return seconds(cfg.Server.ReadTimeout, 15)
+}
+
+func (cfg *Config) GetReadHeaderTimeout() time.Duration {
+	return seconds(cfg.Server.ReadHeaderTimeout, 5)
+}
+
+func (cfg *Config) GetWriteTimeout() time.Duration {
+	return seconds(cfg.Server.WriteTimeout, 30)
+}
+
+func (cfg *Config) GetIdleTimeout() time.Duration {
+	return seconds(cfg.Server.IdleTimeout, 60)
+}
+
+func (cfg *Config) GetShutdownTimeout() time.Duration {
+	return seconds(cfg.Server.ShutdownTimeout, 15)
+}
+
+func (cfg *Config) GetMaxHeaderBytes() int {
+	if cfg.Server.MaxHeaderBytes <= 0 {
+		return 1 << 20
+	}
+	return cfg.Server.MaxHeaderBytes
+}
+
+func (cfg *Config) GetMaxBodyBytes() int64 {
+	if cfg.Server.MaxBodyBytes <= 0 {
+		return 1 << 20
+	}
+	return cfg.Server.MaxBodyBytes
+}
+
+func (cfg *Config) GetUploadDir() string {
+	if cfg.Upload.Dir == "" {
+		return "./data/uploads/"
+	}
+	return cfg.Upload.Dir
+}
+
+func (cfg *Config) GetUploadURLPrefix() string {
+	if cfg.Upload.URLPrefix == "" {
+		return "/uploads/"
+	}
+	return cfg.Upload.URLPrefix
+}
+
+func (cfg *Config) GetMaxAvatarSize() int64 {
+	if cfg.Upload.MaxAvatarSize <= 0 {
+		return 2 << 20
+	}
+	return cfg.Upload.MaxAvatarSize
+}
+
+func (cfg *Config) GetMaxMediaSize() int64 {
+	if cfg.Upload.MaxMediaSize <= 0 {
+		return 10 << 20
+	}
+	return cfg.Upload.MaxMediaSize
+}
+
+// GetAvatarSizes returns square avatar sizes, largest first.
+func (cfg *Config) GetAvatarSizes() []int {
+	if len(cfg.Upload.AvatarSizes) == 0 {
+		return []int{256, 128, 64}
+	}
+	return cfg.Upload.AvatarSizes
+}
+
+func (cfg *Config) GetDefaultAvatar() string {
+	if cfg.Upload.DefaultAvatar == "" {
+		return "/static/default_avatar.png"
+	}
+	return cfg.Upload.DefaultAvatar
+}
+
+// GetCacheControl returns the Cache-Control header of a route.
+func (cfg *Config) GetCacheControl(path string) string {
+	if cc, ok := cfg.HTTP.CacheControl[path]; ok {
+		return cc
+	}
+	return "no-cache"
+}
+
+func (cfg *Config) GetCompressMinSize() int {
+	if cfg.HTTP.CompressMinSize <= 0 {
+		return 1024
+	}
+	return cfg.HTTP.CompressMinSize
+}
+
 func Load(filePath string) error {
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return fmt.Errorf("config file not exists: %s", filePath)
@@ -69,9 +374,24 @@ func Load(filePath string) error {
 		return fmt.Errorf("parse file to config failed: %w", err)
 	}
 
-	if cfg.App.JwtSecret == "" {
+	if cfg.App.JwtSecret == "" && len(cfg.App.JwtKeys) == 0 {
 		return fmt.Errorf("jwt secret is empty")
 	}
+	for _, k := range cfg.App.JwtKeys {
+		if k.Kid == "" {
+			return fmt.Errorf("jwt key id is empty")
+		}
+		if k.RetiredAt != "" {
+			if _, err := time.Parse(time.RFC3339, k.RetiredAt); err != nil {
+				return fmt.Errorf("jwt key %s: retired_at must be RFC3339: %w", k.Kid, err)
+			}
+		}
+	}
+	if cfg.App.JwtGracePeriod != "" {
+		if _, err := time.ParseDuration(cfg.App.JwtGracePeriod); err != nil {
+			return fmt.Errorf("The format of the JWT grace period is incorrect")
+		}
+	}
 	if _, err := time.ParseDuration(cfg.App.JwtExpireTime); err != nil {
 		return fmt.Errorf("The format of the JWT expiration time is incorrect")
 	}
@@ -79,6 +399,52 @@ func Load(filePath string) error {
 		return fmt.Errorf("sensitive words file is empty")
 	}
 
+	for _, size := range cfg.Upload.AvatarSizes {
+		if size <= 0 || size > 1024 {
+			return fmt.Errorf("avatar size must be in 1..1024: %d", size)
+		}
+	}
+	if p := cfg.Upload.URLPrefix; p != "" && (!strings.HasPrefix(p, "/") || !strings.HasSuffix(p, "/")) {
+		return fmt.Errorf("upload url_prefix must start and end with '/'")
+	}
+
+	if cfg.Cache.BreakerOpenTimeout != "" {
+		if _, err := time.ParseDuration(cfg.Cache.BreakerOpenTimeout); err != nil {
+			return fmt.Errorf("The format of the cache breaker open timeout is incorrect")
+		}
+	}
+	switch cfg.Cache.Driver {
+	case "", "redis", "memory":
+	default:
+		return fmt.Errorf("cache driver must be redis or memory")
+	}
+
+	switch cfg.Session.SameSite {
+	case "", "lax", "strict", "none":
+	default:
+		return fmt.Errorf("session same_site must be lax, strict or none")
+	}
+	if cfg.Session.SameSite == "none" && !cfg.Session.CookieSecure {
+		return fmt.Errorf("session same_site none requires cookie_secure")
+	}
+
+	if base := cfg.Site.BaseURL; base != "" {
+		u, err := url.Parse(base)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("site base_url must be an absolute http(s) url: %q", base)
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, p := range cfg.OAuth.Providers {
+		if p.Name == "" || names[p.Name] {
+			return fmt.Errorf("oauth provider name is empty or duplicated: %q", p.Name)
+		}
+		names[p.Name] = true
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(cfg.Upload.AvatarSizes)))
+
 	Cfg = cfg
 	return nil
 }
@@ -3,11 +3,16 @@ package handler
 import (
 	"net/http"
 
+	"github.com/gngtwhh/WBlog/internal/config"
 	"github.com/gngtwhh/WBlog/internal/render"
 )
 
 func (h *IndexHandler) Admin(w http.ResponseWriter, r *http.Request) {
-	// Render admin.html, no data needed for now,
+	// Render admin.html, only the page head is rendered here,
 	// data will be loaded asynchronously via JS
-	render.Execute(w, "admin", nil)
+	render.Execute(w, r, "admin", Meta{
+		Title:    "后台管理",
+		SiteName: config.Cfg.GetSiteTitle(),
+		NoIndex:  true,
+	})
 }
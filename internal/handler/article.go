@@ -1,12 +1,13 @@
 package handler
 
 import (
-	"encoding/json"
-	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/gngtwhh/WBlog/internal/middleware"
 	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/internal/repository"
 	"github.com/gngtwhh/WBlog/internal/service"
 	"github.com/gngtwhh/WBlog/pkg/errcode"
 	"github.com/gngtwhh/WBlog/pkg/response"
@@ -18,63 +19,162 @@ type ArticleHandler struct {
 
 // CreateArticleRequest bind POST request data, and will be cleaned to match model.Article
 type CreateArticleRequest struct {
-	Title    string `json:"title"`
-	Author   string `json:"author"`
-	Content  string `json:"content"`
-	Abstract string `json:"abstract"`
+	Title    string `json:"title" validate:"required,max=200"`
+	Author   string `json:"author" validate:"max=64"`
+	Content  string `json:"content" validate:"required"`
+	Abstract string `json:"abstract" validate:"max=500"`
+	Status   string `json:"status" validate:"enum=published|draft"` // empty publishes, or keeps the status on update
 }
 
 // UpdateArticleRequest bind POST request data, and will be cleaned to match model.Article
 type UpdateArticleRequest struct {
-	ID uint64 `json:"id"`
+	ID uint64 `json:"id" validate:"required"`
 	CreateArticleRequest
 }
 
+// ListArticlesQuery binds the sort and filter params of article lists.
+// Dates are UTC days, both ends included. Readers only see published
+// articles, status is for admins.
+type ListArticlesQuery struct {
+	Sort    string `json:"sort" validate:"enum=created_at|updated_at|view_count|comment_count"`
+	Order   string `json:"order" validate:"enum=desc|asc"`
+	Author  string `json:"author" validate:"max=64"`
+	Status  string `json:"status" validate:"enum=published|draft|all"`
+	From    string `json:"from" validate:"pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$"`
+	To      string `json:"to" validate:"pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$"`
+	Compact bool   `json:"compact"` // omit abstracts
+}
+
 func NewArticleHandler(svc *service.ArticleService) *ArticleHandler {
 	return &ArticleHandler{svc: svc}
 }
 
 // ListArticles handle a GET request to gen a list of Atricles
 // GET req requires two params:
-// @page: page index(start from 1)
-// @pagesize: count of articles per-page
+// @page: page index(start from 1), not needed with cursor
+// @pagesize: count of articles per-page, at most 100
+// and takes optional ones:
+// @cursor: next_cursor of the previous page, empty for the first page
+// and those of ListArticlesQuery
 func (h *ArticleHandler) ListArticles(w http.ResponseWriter, r *http.Request) {
 	pageSize := r.URL.Query().Get("pagesize")
 	page := r.URL.Query().Get("page")
 
 	pageSizeInt, err := strconv.Atoi(pageSize)
 	if err != nil {
-		response.Fail(w, errcode.ParamError, "Invalid param: pagesize")
+		invalidParam(w, "pagesize", "must be an integer")
 		// http.Error(w, "Invalid page size", http.StatusBadRequest)
 		return
 	}
 	pageInt, err := strconv.Atoi(page)
-	if err != nil {
-		response.Fail(w, errcode.ParamError, "Invalid param: page")
+	if err != nil && !r.URL.Query().Has("cursor") {
+		invalidParam(w, "page", "must be an integer")
 		// http.Error(w, "Invalid page", http.StatusBadRequest)
 		return
 	}
 
-	if pageSizeInt <= 0 {
-		pageSizeInt = 10 // default 10 articles per-page
-	}
 	if pageInt <= 0 {
 		pageInt = 1
 	}
+	h.list(w, r, pageInt, clampPageSize(pageSizeInt))
+}
 
-	offset := (pageInt - 1) * pageSizeInt
-	articles, err := h.svc.ListArticles(pageSizeInt, offset)
+// list replies a Page of articles by offset or, if asked, by cursor.
+func (h *ArticleHandler) list(w http.ResponseWriter, r *http.Request, page, pageSize int) {
+	q, ok := articleQuery(w, r)
+	if !ok {
+		return
+	}
+	cursor, keyset, ok := parseCursor(w, r)
+	if !ok {
+		return
+	}
+	if keyset && q.Sort != repository.SortCreated {
+		invalidParam(w, "cursor", "requires sort=created_at")
+		return
+	}
+	total, err := h.svc.Count(r.Context(), q)
 	if err != nil {
-		response.Fail(w, errcode.ServerError)
-		// http.Error(w, "Internal server error", http.StatusInternalServerError)
+		response.Error(w, err)
 		return
 	}
-	response.Success(w, articles)
+
+	// no Last-Modified: a deleted article does not move the newest
+	// updated_at, only the ETag notices the change
+	if keyset {
+		q.Cursor, q.Limit = cursor, pageSize+1
+		articles, err := h.svc.ListArticles(r.Context(), q)
+		if err != nil {
+			response.Error(w, err)
+			return
+		}
+		response.Success(w, cursorPage(r, articles, total, pageSize, func(a model.Article) *repository.Cursor {
+			return repository.CursorAfter(a.CreatedAt, a.ID)
+		}))
+		return
+	}
+	q.Limit, q.Offset = pageSize, (page-1)*pageSize
+	articles, err := h.svc.ListArticles(r.Context(), q)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, offsetPage(r, articles, total, page, pageSize))
 }
 
-// Count handles GET req, and returns the total number of articles.
+// articleQuery builds the query of a list from ListArticlesQuery params,
+// replying ParamError on bad ones and Forbidden if a reader asks for
+// drafts.
+func articleQuery(w http.ResponseWriter, r *http.Request) (repository.ArticleQuery, bool) {
+	var params ListArticlesQuery
+	if !bindQuery(w, r, &params) {
+		return repository.ArticleQuery{}, false
+	}
+	q := repository.ArticleQuery{
+		Sort:    repository.SortCreated,
+		Asc:     params.Order == "asc",
+		Author:  params.Author,
+		Status:  params.Status,
+		Compact: params.Compact,
+	}
+	if params.Sort != "" {
+		q.Sort = repository.ArticleSort(params.Sort)
+	}
+	if !middleware.IsAdmin(r) {
+		if q.Status != "" && q.Status != model.ArticlePublished {
+			response.Fail(w, errcode.Forbidden)
+			return repository.ArticleQuery{}, false
+		}
+		q.Status = model.ArticlePublished
+	} else if q.Status == "all" {
+		q.Status = ""
+	}
+
+	var err error
+	if params.From != "" {
+		if q.From, err = time.Parse(time.DateOnly, params.From); err != nil {
+			invalidParam(w, "from", "must be a date")
+			return repository.ArticleQuery{}, false
+		}
+	}
+	if params.To != "" {
+		if q.To, err = time.Parse(time.DateOnly, params.To); err != nil {
+			invalidParam(w, "to", "must be a date")
+			return repository.ArticleQuery{}, false
+		}
+		q.To = q.To.AddDate(0, 0, 1) // include the whole day
+	}
+	return q, true
+}
+
+// visible hides drafts from everyone but admins.
+func visible(r *http.Request, article model.Article) bool {
+	return article.Status != model.ArticleDraft || middleware.IsAdmin(r)
+}
+
+// Count handles GET req, and returns the total number of published articles.
 func (h *ArticleHandler) Count(w http.ResponseWriter, r *http.Request) {
-	count, err := h.svc.Count()
+	count, err := h.svc.Count(r.Context(), repository.ArticleQuery{Status: model.ArticlePublished})
 	if err != nil {
 		response.Fail(w, errcode.ServerError)
 		// http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -90,21 +190,21 @@ func (h *ArticleHandler) GetArticle(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		response.Fail(w, errcode.ParamError, "Invalid param: id")
+		invalidParam(w, "id", "must be a positive integer")
 		// http.Error(w, "Invalid article ID", http.StatusBadRequest)
 		return
 	}
 
-	article, err := h.svc.GetArticle(int64(id))
+	article, err := h.svc.GetArticle(r.Context(), int64(id))
+	if err == nil && !visible(r, article) {
+		err = service.ErrArticleNotFound
+	}
 	if err != nil {
-		if errors.Is(err, service.ErrArticleNotFound) {
-			response.Fail(w, errcode.ArticleNotFound)
-			return
-		}
-		response.Fail(w, errcode.ServerError)
-		// http.Error(w, "Internal server error", http.StatusInternalServerError)
+		response.Error(w, err)
 		return
 	}
+	// no Last-Modified, view_count and comment_count change without
+	// updated_at; the ETag of the body covers them
 	response.Success(w, article)
 }
 
@@ -113,15 +213,7 @@ func (h *ArticleHandler) GetArticle(w http.ResponseWriter, r *http.Request) {
 func (h *ArticleHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req CreateArticleRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Fail(w, errcode.ParamError, "Invalid json request body")
-		// http.Error(w, "Invalid json request body", http.StatusBadRequest)
-		return
-	}
-
-	if req.Title == "" || req.Content == "" {
-		response.Fail(w, errcode.ParamError, "Title and content must not be empty")
-		// http.Error(w, "Title and content must not be empty", http.StatusBadRequest)
+	if !bind(w, r, &req) {
 		return
 	}
 
@@ -130,8 +222,9 @@ func (h *ArticleHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Author:   req.Author,
 		Content:  req.Content,
 		Abstract: req.Abstract,
+		Status:   req.Status,
 	}
-	err := h.svc.Create(&article)
+	err := h.svc.Create(r.Context(), &article)
 	if err != nil {
 		response.Fail(w, errcode.ServerError)
 		// http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -144,37 +237,21 @@ func (h *ArticleHandler) Create(w http.ResponseWriter, r *http.Request) {
 // POST data must bind to UpdateArticleRequest.
 func (h *ArticleHandler) Update(w http.ResponseWriter, r *http.Request) {
 	var req UpdateArticleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Fail(w, errcode.ParamError, "Invalid json request body")
-		// http.Error(w, "Invalid json request body", http.StatusBadRequest)
+	if !bind(w, r, &req) {
 		return
 	}
 
-	if req.ID == 0 {
-		response.Fail(w, errcode.ParamError, "Need ID of the article")
-		// http.Error(w, "Need ID of the article", http.StatusBadRequest)
-	}
-	// // update allow empty content?
-	// if req.Title == "" || req.Content == "" {
-	// 	http.Error(w, "Title and content must not be empty", http.StatusBadRequest)
-	// 	return
-	// }
-
 	article := model.Article{
 		ID:       req.ID,
 		Title:    req.Title,
 		Author:   req.Author,
 		Content:  req.Content,
 		Abstract: req.Abstract,
+		Status:   req.Status,
 	}
-	err := h.svc.Update(&article)
+	err := h.svc.Update(r.Context(), &article)
 	if err != nil {
-		if errors.Is(err, service.ErrArticleNotFound) {
-			response.Fail(w, errcode.ArticleNotFound)
-			return
-		}
-		response.Fail(w, errcode.ServerError)
-		// http.Error(w, "Internal server error", http.StatusInternalServerError)
+		response.Error(w, err)
 		return
 	}
 	response.Success(w, map[string]uint64{"id": article.ID})
@@ -186,19 +263,14 @@ func (h *ArticleHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	idInt, err := strconv.Atoi(idStr)
 	if err != nil {
-		response.Fail(w, errcode.ParamError, "Invalid param: id")
+		invalidParam(w, "id", "must be a positive integer")
 		// http.Error(w, "Invalid article ID", http.StatusBadRequest)
 		return
 	}
 
-	err = h.svc.Delete(int64(idInt))
+	err = h.svc.Delete(r.Context(), int64(idInt))
 	if err != nil {
-		if errors.Is(err, service.ErrArticleNotFound) {
-			response.Fail(w, errcode.ArticleNotFound)
-			return
-		}
-		response.Fail(w, errcode.ServerError)
-		// http.Error(w, "Internal server error", http.StatusInternalServerError)
+		response.Error(w, err)
 		return
 	}
 	response.Success(w, nil)
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/response"
+	"github.com/gngtwhh/WBlog/pkg/validate"
+)
+
+// bind decodes the JSON body of r into dst and validates its tags.
+// Unknown fields are rejected, the body size is capped by
+// middleware.LimitBody. On failure it replies and returns false.
+func bind(w http.ResponseWriter, r *http.Request, dst any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.Fail(w, errcode.BodyTooLarge)
+			return false
+		}
+		invalidParam(w, "body", "invalid json: "+err.Error())
+		return false
+	}
+	if dec.More() {
+		invalidParam(w, "body", "unexpected data after the object")
+		return false
+	}
+
+	return check(w, dst)
+}
+
+// bindQuery sets the string, bool and int fields of the struct dst from
+// the query params named by their json tags, then validates its tags.
+// Params without a field are ignored. On failure it replies and returns
+// false.
+func bindQuery(w http.ResponseWriter, r *http.Request, dst any) bool {
+	query := r.URL.Query()
+	rv := reflect.ValueOf(dst).Elem()
+	var errs validate.Errors
+	for i := 0; i < rv.NumField(); i++ {
+		name, _, _ := strings.Cut(rv.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || !query.Has(name) {
+			continue
+		}
+		value, fv := query.Get(name), rv.Field(i)
+		switch fv.Kind() {
+		case reflect.String:
+			fv.SetString(value)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, validate.FieldError{Field: name, Rule: "type", Param: "boolean", Message: "must be true or false"})
+				continue
+			}
+			fv.SetBool(b)
+		case reflect.Int, reflect.Int64:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				errs = append(errs, validate.FieldError{Field: name, Rule: "type", Param: "integer", Message: "must be an integer"})
+				continue
+			}
+			fv.SetInt(n)
+		default:
+			panic("bindQuery: unsupported field type " + fv.Type().String())
+		}
+	}
+	if err := validate.Struct(dst); err != nil {
+		var ruleErrs validate.Errors
+		errors.As(err, &ruleErrs)
+		errs = append(errs, ruleErrs...)
+	}
+	if len(errs) > 0 {
+		invalid(w, errs)
+		return false
+	}
+	return true
+}
+
+// check validates the tags of dst, replying the field errors if any fail.
+func check(w http.ResponseWriter, dst any) bool {
+	if err := validate.Struct(dst); err != nil {
+		var errs validate.Errors
+		errors.As(err, &errs)
+		invalid(w, errs)
+		return false
+	}
+	return true
+}
+
+func invalid(w http.ResponseWriter, errs validate.Errors) {
+	response.Error(w, &errcode.Error{
+		Code: errcode.ParamError,
+		Err:  errs,
+		Data: map[string]any{"errors": errs},
+	})
+}
+
+// invalidParam replies ParamError for a single malformed param; the
+// detail goes in data.errors so the message itself stays localized.
+func invalidParam(w http.ResponseWriter, field, message string) {
+	invalid(w, validate.Errors{{Field: field, Rule: "invalid", Message: message}})
+}
@@ -1,13 +1,13 @@
 package handler
 
 import (
-	"encoding/json"
-	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gngtwhh/WBlog/internal/middleware"
 	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/internal/repository"
 	"github.com/gngtwhh/WBlog/internal/service"
 	"github.com/gngtwhh/WBlog/pkg/errcode"
 	"github.com/gngtwhh/WBlog/pkg/response"
@@ -19,8 +19,8 @@ type CommentHandler struct {
 }
 
 type CreateCommentReq struct {
-	ArticleID int64  `json:"article_id"`
-	Content   string `json:"content"`
+	ArticleID int64  `json:"article_id" validate:"required,min=1"`
+	Content   string `json:"content" validate:"required,max=2000"`
 }
 
 func NewCommentHandler(commentsvc *service.CommentService, articlesvc *service.ArticleService) *CommentHandler {
@@ -38,27 +38,13 @@ func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req CreateCommentReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Fail(w, errcode.ParamError, "Invalid json request body")
+	if !bind(w, r, &req) {
 		return
 	}
 
-	if req.ArticleID <= 0 {
-		response.Fail(w, errcode.ParamError, "Invalid article ID")
+	if !h.commentable(w, r, req.ArticleID) {
 		return
 	}
-	if req.Content == "" {
-		response.Fail(w, errcode.ParamError, "Comment content cannot be empty")
-		return
-	}
-
-	if _, err := h.articlesvc.GetArticle(req.ArticleID); err != nil {
-		if errors.Is(err, service.ErrArticleNotFound) {
-			response.Fail(w, errcode.ArticleNotFound)
-			return
-		}
-		response.Fail(w, errcode.ServerError)
-	}
 
 	username, _ := middleware.GetUsername(r)
 	comment := &model.Comment{
@@ -68,44 +54,103 @@ func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 		Content:   req.Content,
 	}
 
-	if err := h.commentsvc.Create(comment); err != nil {
+	if err := h.commentsvc.Create(r.Context(), comment); err != nil {
 		response.Fail(w, errcode.ServerError)
 		return
 	}
+	h.articlesvc.Refresh(r.Context(), req.ArticleID)
 	response.Success(w, map[string]uint64{"id": comment.ID})
 }
 
+// commentable replies ArticleNotFound unless the article exists and is
+// published.
+func (h *CommentHandler) commentable(w http.ResponseWriter, r *http.Request, articleID int64) bool {
+	article, err := h.articlesvc.GetArticle(r.Context(), articleID)
+	if err == nil && article.Status == model.ArticleDraft {
+		err = service.ErrArticleNotFound
+	}
+	if err != nil {
+		response.Error(w, err)
+		return false
+	}
+	return true
+}
+
 func (h *CommentHandler) ListComments(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	articleIDStr := query.Get("article_id")
 	articleID, err := strconv.ParseInt(articleIDStr, 10, 64)
 	if err != nil || articleID <= 0 {
-		response.Fail(w, errcode.ParamError, "Invalid or missing article_id")
+		invalidParam(w, "article_id", "must be a positive integer")
 		return
 	}
 
-	pageStr := query.Get("page")
-	pageSizeStr := query.Get("page_size")
-	page, _ := strconv.Atoi(pageStr)
-	if page <= 0 {
-		page = 1
+	page, pageSize := pageParams(r)
+	h.list(w, r, articleID, page, pageSize)
+}
+
+// list replies a Page of comments by offset or, if asked, by cursor.
+func (h *CommentHandler) list(w http.ResponseWriter, r *http.Request, articleID int64, page, pageSize int) {
+	cursor, keyset, ok := parseCursor(w, r)
+	if !ok {
+		return
 	}
-	pageSize, _ := strconv.Atoi(pageSizeStr)
-	if pageSize <= 0 {
-		pageSize = 10 // Default page size
-	} else if pageSize > 100 {
-		pageSize = 100 // Max limit
+	total, err := h.commentsvc.Count(r.Context(), articleID)
+	if err != nil {
+		response.Error(w, err)
+		return
 	}
 
-	offset := (page - 1) * pageSize
-	comments, err := h.commentsvc.List(articleID, pageSize, offset)
+	if keyset {
+		comments, err := h.commentsvc.ListAfter(r.Context(), articleID, cursor, pageSize+1)
+		if err != nil {
+			response.Error(w, err)
+			return
+		}
+		p := cursorPage(r, comments, total, pageSize, func(c *model.Comment) *repository.Cursor {
+			return repository.CursorAfter(c.CreatedAt, c.ID)
+		})
+		setCommentsLastModified(w, p.Items)
+		response.Success(w, p)
+		return
+	}
+	comments, err := h.commentsvc.List(r.Context(), articleID, pageSize, (page-1)*pageSize)
 	if err != nil {
-		response.Fail(w, errcode.ServerError)
+		response.Error(w, err)
 		return
 	}
+	setCommentsLastModified(w, comments)
+	response.Success(w, offsetPage(r, comments, total, page, pageSize))
+}
 
-	if comments == nil {
-		comments = []*model.Comment{}
+// pageParams reads the optional page and page_size query params,
+// page_size defaults to 10 and is capped at 100.
+func pageParams(r *http.Request) (page, pageSize int) {
+	query := r.URL.Query()
+	page, _ = strconv.Atoi(query.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ = strconv.Atoi(query.Get("page_size"))
+	return page, clampPageSize(pageSize)
+}
+
+// clampPageSize defaults a page size to 10 and limits it to 100, the
+// same for every list of both API versions.
+func clampPageSize(pageSize int) int {
+	if pageSize <= 0 {
+		return 10 // Default page size
+	}
+	return min(pageSize, 100) // Max limit
+}
+
+// setCommentsLastModified sets Last-Modified to the newest comment.
+func setCommentsLastModified(w http.ResponseWriter, comments []*model.Comment) {
+	var lastModified time.Time
+	for _, c := range comments {
+		if c.CreatedAt.After(lastModified) {
+			lastModified = c.CreatedAt
+		}
 	}
-	response.Success(w, comments)
+	middleware.SetLastModified(w, lastModified)
 }
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gngtwhh/WBlog/internal/config"
+	"github.com/gngtwhh/WBlog/internal/middleware"
+	"github.com/gngtwhh/WBlog/internal/service"
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/feed"
+	"github.com/gngtwhh/WBlog/pkg/response"
+)
+
+// FeedHandler serves the syndication feeds, not wrapped in the response
+// envelope.
+type FeedHandler struct {
+	svc *service.FeedService
+}
+
+func NewFeedHandler(svc *service.FeedService) *FeedHandler {
+	return &FeedHandler{svc: svc}
+}
+
+// RSS handles GET /feed.xml.
+func (h *FeedHandler) RSS(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, feed.RSS)
+}
+
+// Atom handles GET /atom.xml.
+func (h *FeedHandler) Atom(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, feed.Atom)
+}
+
+// JSON handles GET /feed.json.
+func (h *FeedHandler) JSON(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, feed.JSON)
+}
+
+// serve replies 404 without site.base_url, feed links must be absolute.
+func (h *FeedHandler) serve(w http.ResponseWriter, r *http.Request, format feed.Format) {
+	base := config.Cfg.GetBaseURL()
+	if base == "" {
+		response.Fail(w, errcode.NotFound)
+		return
+	}
+	f, err := h.svc.Render(r.Context(), format, base, r.URL.Path)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	middleware.SetLastModified(w, f.Modified)
+	w.Write(f.Body)
+}
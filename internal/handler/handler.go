@@ -2,8 +2,14 @@ package handler
 
 // App contains all handlers
 type App struct {
-	Index   *IndexHandler
-	Article *ArticleHandler
-	User    *UserHandler
-	Comment *CommentHandler
+	Index     *IndexHandler
+	Article   *ArticleHandler
+	User      *UserHandler
+	Comment   *CommentHandler
+	OAuth     *OAuthHandler
+	WellKnown *WellKnownHandler
+	Media     *MediaHandler
+	Health    *HealthHandler
+	Feed      *FeedHandler
+	Sitemap   *SitemapHandler
 }
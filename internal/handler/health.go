@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gngtwhh/WBlog/internal/cache"
+)
+
+// HealthCheck probes one dependency. Non-critical checks report
+// "degraded" instead of failing readiness.
+type HealthCheck struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) error
+	Detail   func() any // optional state shown with the result
+}
+
+type checkResult struct {
+	Status    string  `json:"status"` // up, down, degraded
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	Detail    any     `json:"detail,omitempty"`
+}
+
+// HealthHandler serves the liveness, readiness and build info probes.
+// Replies are plain JSON, not wrapped in the response envelope.
+type HealthHandler struct {
+	checks  []HealthCheck
+	timeout time.Duration
+}
+
+func NewHealthHandler(checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks, timeout: 2 * time.Second}
+}
+
+// CacheCheck reports the cache and the state of its breaker, if any. The
+// breaker falls back to the local cache, so the check is not critical.
+func CacheCheck(store cache.Store) HealthCheck {
+	return HealthCheck{
+		Name: "cache",
+		Check: func(ctx context.Context) error {
+			if err := cache.Ping(ctx, store); err != nil {
+				return err
+			}
+			if cache.Degraded(store) {
+				return errors.New("breaker not closed, serving from the local fallback")
+			}
+			return nil
+		},
+		Detail: func() any {
+			if h, ok := cache.HealthOf(store); ok {
+				return h
+			}
+			return nil
+		},
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Healthz reports the process is alive.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz runs every check and answers 503 if a critical one fails.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	status, code := "ok", http.StatusOK
+	results := make(map[string]checkResult, len(h.checks))
+	for _, c := range h.checks {
+		start := time.Now()
+		err := c.Check(ctx)
+		res := checkResult{
+			Status:    "up",
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if err != nil {
+			res.Error = err.Error()
+			if c.Critical {
+				res.Status = "down"
+				status, code = "unavailable", http.StatusServiceUnavailable
+			} else {
+				res.Status = "degraded"
+				if status == "ok" {
+					status = "degraded"
+				}
+			}
+		}
+		if c.Detail != nil {
+			res.Detail = c.Detail()
+		}
+		results[c.Name] = res
+	}
+	writeJSON(w, code, map[string]any{"status": status, "checks": results})
+}
+
+// Version exposes the module version and VCS info embedded by go build.
+func (h *HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]string{"version": "unknown"})
+		return
+	}
+	v := map[string]string{
+		"version":    info.Main.Version,
+		"go_version": info.GoVersion,
+		"module":     info.Main.Path,
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision", "vcs.time", "vcs.modified":
+			v[s.Key[len("vcs."):]] = s.Value
+		}
+	}
+	writeJSON(w, http.StatusOK, v)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gngtwhh/WBlog/internal/cache"
+)
+
+// downStore is a cache whose every call fails.
+type downStore struct{ *cache.LRUStore }
+
+var errDown = errors.New("connection refused")
+
+func (downStore) Get(context.Context, string) (string, error)              { return "", errDown }
+func (downStore) Set(context.Context, string, string, time.Duration) error { return errDown }
+func (downStore) Ping(context.Context) error                               { return errDown }
+
+// TestReadyzCacheBreaker trips the breaker and checks /readyz reports it.
+func TestReadyzCacheBreaker(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	breaker := cache.NewBreakerStore(downStore{cache.NewLRUStore(10)}, cache.NewLRUStore(10),
+		cache.BreakerOptions{FailureThreshold: 2, OpenTimeout: time.Minute}, logger)
+	store := cache.NewTracedStore(breaker)
+	for i := 0; i < 2; i++ {
+		store.Get(context.Background(), "k")
+	}
+
+	rec := httptest.NewRecorder()
+	NewHealthHandler(CacheCheck(store)).Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var body struct {
+		Status string `json:"status"`
+		Checks map[string]struct {
+			Status string       `json:"status"`
+			Detail cache.Health `json:"detail"`
+		} `json:"checks"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || body.Status != "degraded" {
+		t.Errorf("readyz = %d %q, want 200 degraded", rec.Code, body.Status)
+	}
+	got := body.Checks["cache"]
+	if got.Status != "degraded" || got.Detail.State != cache.StateOpen || got.Detail.Failures != 2 || got.Detail.LastError != errDown.Error() {
+		t.Errorf("cache check = %+v, want degraded, open after 2 failures of %q", got, errDown)
+	}
+}
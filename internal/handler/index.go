@@ -1,17 +1,73 @@
 package handler
 
 import (
+	"errors"
+	"html/template"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/gngtwhh/WBlog/internal/config"
+	"github.com/gngtwhh/WBlog/internal/model"
 	"github.com/gngtwhh/WBlog/internal/render"
+	"github.com/gngtwhh/WBlog/internal/repository"
 	"github.com/gngtwhh/WBlog/internal/service"
 	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/markdown"
 	"github.com/gngtwhh/WBlog/pkg/response"
 )
 
-type IndexData struct {
-	Title string `json:"title"`
-	Desc  string `json:"desc"`
+// indexPageSize is the number of articles per home page.
+const indexPageSize = 10
+
+// Meta is what the layout puts in <head> for crawlers and link previews.
+// Pages embed it, so templates reach its fields directly.
+type Meta struct {
+	Title       string // page title, empty on the home page
+	Description string
+	Canonical   string // absolute URL, empty omits canonical and og:url
+	Type        string // og:type, "website" or "article"
+	SiteName    string
+	NoIndex     bool
+	JSONLD      any // structured data, marshalled into a ld+json script
+}
+
+// IndexPage is the data of index.html.
+type IndexPage struct {
+	Meta
+	Articles []model.Article
+	Total    int64
+	Page     int
+	Prev     string // link to the newer page, empty on the first
+	Next     string // link to the older page, empty on the last
+}
+
+// ArticlePage is the data of article.html.
+type ArticlePage struct {
+	Meta
+	Article model.Article
+	Content template.HTML // rendered Markdown
+}
+
+// blogPosting is a schema.org BlogPosting, https://schema.org/BlogPosting
+type blogPosting struct {
+	Context       string    `json:"@context"`
+	Type          string    `json:"@type"`
+	Headline      string    `json:"headline"`
+	Description   string    `json:"description,omitempty"`
+	URL           string    `json:"url,omitempty"`
+	MainEntity    string    `json:"mainEntityOfPage,omitempty"`
+	DatePublished time.Time `json:"datePublished"`
+	DateModified  time.Time `json:"dateModified"`
+	Author        person    `json:"author"`
+	Publisher     person    `json:"publisher"`
+}
+
+type person struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
 }
 
 type IndexHandler struct {
@@ -22,19 +78,152 @@ func NewIndexHandler(svc *service.ArticleService) *IndexHandler {
 	return &IndexHandler{articleSvc: svc}
 }
 
-// Index returns a json data
+// Index serves the home page and is the catch-all of unknown paths: API
+// paths get the JSON NotFound, any other the 404 page.
 func (h *IndexHandler) Index(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path != "/" {
+	if r.URL.Path == "/" {
+		h.IndexHtml(w, r)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/api/") {
 		response.Fail(w, errcode.NotFound)
 		return
 	}
-	h.IndexHtml(w, r)
+	h.NotFound(w, r)
 }
 
+// IndexHtml renders a page of published articles, newest first; the page
+// number is the optional ?page= param.
 func (h *IndexHandler) IndexHtml(w http.ResponseWriter, r *http.Request) {
-	render.Execute(w, "index", nil)
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	q := repository.ArticleQuery{
+		Status: model.ArticlePublished,
+		Limit:  indexPageSize,
+		Offset: (page - 1) * indexPageSize,
+	}
+	articles, err := h.articleSvc.ListArticles(r.Context(), q)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	total, err := h.articleSvc.Count(r.Context(), q.Filter())
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	if page > 1 && len(articles) == 0 {
+		h.NotFound(w, r)
+		return
+	}
+
+	data := IndexPage{
+		Meta: Meta{
+			Description: config.Cfg.Site.Description,
+			Canonical:   canonical(indexLink(page)),
+			Type:        "website",
+			SiteName:    config.Cfg.GetSiteTitle(),
+		},
+		Articles: articles,
+		Total:    total,
+		Page:     page,
+	}
+	if page > 1 {
+		data.Prev = indexLink(page - 1)
+	}
+	if int64(page*indexPageSize) < total {
+		data.Next = indexLink(page + 1)
+	}
+	render.Execute(w, r, "index", data)
+}
+
+func indexLink(page int) string {
+	if page == 1 {
+		return "/"
+	}
+	return "/?page=" + strconv.Itoa(page)
 }
 
+// ArticlePage renders a published article, with its Markdown rendered to
+// HTML. Drafts and unknown ids get the 404 page.
 func (h *IndexHandler) ArticlePage(w http.ResponseWriter, r *http.Request) {
-	render.Execute(w, "article", nil)
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		h.NotFound(w, r)
+		return
+	}
+	article, err := h.articleSvc.GetArticle(r.Context(), id)
+	if err == nil && article.Status == model.ArticleDraft {
+		err = service.ErrArticleNotFound
+	}
+	if errors.Is(err, service.ErrArticleNotFound) {
+		h.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	content, err := markdown.Render(article.Content)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	site := config.Cfg.GetSiteTitle()
+	url := canonical("/article/" + strconv.FormatInt(id, 10))
+	desc := summary(article.Abstract, 160)
+	render.Execute(w, r, "article", ArticlePage{
+		Meta: Meta{
+			Title:       article.Title,
+			Description: desc,
+			Canonical:   url,
+			Type:        "article",
+			SiteName:    site,
+			JSONLD: blogPosting{
+				Context:       "https://schema.org",
+				Type:          "BlogPosting",
+				Headline:      article.Title,
+				Description:   desc,
+				URL:           url,
+				MainEntity:    url,
+				DatePublished: article.CreatedAt,
+				DateModified:  article.UpdatedAt,
+				Author:        person{Type: "Person", Name: article.Author},
+				Publisher:     person{Type: "Organization", Name: site},
+			},
+		},
+		Article: article,
+		Content: template.HTML(content), // goldmark drops raw html
+	})
+}
+
+// NotFound renders the 404 page.
+func (h *IndexHandler) NotFound(w http.ResponseWriter, r *http.Request) {
+	render.ExecuteStatus(w, r, http.StatusNotFound, "404", Meta{
+		Title:    "404",
+		SiteName: config.Cfg.GetSiteTitle(),
+		NoIndex:  true,
+	})
+}
+
+// canonical returns the absolute URL of path, empty without site.base_url;
+// the Host header is up to the client and must not end up in links.
+func canonical(path string) string {
+	base := config.Cfg.GetBaseURL()
+	if base == "" {
+		return ""
+	}
+	return base + path
+}
+
+// summary cuts s to at most n runes, for meta descriptions.
+func summary(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
 }
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gngtwhh/WBlog/internal/config"
+	"github.com/gngtwhh/WBlog/internal/middleware"
+	"github.com/gngtwhh/WBlog/internal/service"
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/response"
+)
+
+type MediaHandler struct {
+	svc *service.MediaService
+}
+
+func NewMediaHandler(svc *service.MediaService) *MediaHandler {
+	return &MediaHandler{svc: svc}
+}
+
+// Upload handles a multipart POST with the file in field "file".
+func (h *MediaHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		response.Fail(w, errcode.TokenInvalid)
+		return
+	}
+	data, filename, ok := readFormFile(w, r, "file", config.Cfg.GetMaxMediaSize())
+	if !ok {
+		return
+	}
+
+	media, err := h.svc.Upload(r.Context(), userID, filename, data)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, media)
+}
+
+// List handles a GET request to list media, newest first.
+// GET req requires two params:
+// @page: page index(start from 1)
+// @pagesize: count of media per-page
+func (h *MediaHandler) List(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("pagesize"))
+	if pageSize <= 0 {
+		pageSize = 20
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	list, total, err := h.svc.List(pageSize, (page-1)*pageSize)
+	if err != nil {
+		response.Fail(w, errcode.ServerError)
+		return
+	}
+	response.Success(w, map[string]any{
+		"list":  list,
+		"total": total,
+	})
+}
+
+// DELETE req requires one param:
+// @id: id of media required
+func (h *MediaHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		invalidParam(w, "id", "must be a positive integer")
+		return
+	}
+	if err := h.svc.Delete(r.Context(), id); err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, nil)
+}
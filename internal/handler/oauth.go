@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gngtwhh/WBlog/internal/config"
+	"github.com/gngtwhh/WBlog/internal/middleware"
+	"github.com/gngtwhh/WBlog/internal/service"
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/response"
+)
+
+const oauthCookiePrefix = "oauth_"
+
+type OAuthHandler struct {
+	svc *service.OAuthService
+}
+
+func NewOAuthHandler(svc *service.OAuthService) *OAuthHandler {
+	return &OAuthHandler{svc: svc}
+}
+
+// Providers returns the configured login providers.
+func (h *OAuthHandler) Providers(w http.ResponseWriter, r *http.Request) {
+	response.Success(w, map[string][]string{"providers": h.svc.Providers()})
+}
+
+// Login redirects to the provider, state and PKCE verifier are kept in
+// a short-lived HttpOnly cookie until the callback.
+func (h *OAuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	provider := r.PathValue("provider")
+	authURL, state, verifier, err := h.svc.AuthURL(provider)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthCookiePrefix + provider,
+		Value:    state + "." + verifier,
+		Path:     "/api/oauth/" + provider,
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback handles the redirect back from the provider.
+// GET req params are set by provider:
+// @code: authorization code
+// @state: must equal the state stored in cookie
+func (h *OAuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	provider := r.PathValue("provider")
+	query := r.URL.Query()
+	if errStr := query.Get("error"); errStr != "" {
+		middleware.GetLogger(r.Context()).Warn("oauth provider error", "provider", provider, "error", errStr)
+		response.Fail(w, errcode.OAuthFailed)
+		return
+	}
+
+	cookie, err := r.Cookie(oauthCookiePrefix + provider)
+	if err != nil {
+		middleware.GetLogger(r.Context()).Warn("oauth state missing or expired", "provider", provider)
+		response.Fail(w, errcode.OAuthFailed)
+		return
+	}
+	// one-shot, clear it whatever happens next
+	http.SetCookie(w, &http.Cookie{Name: cookie.Name, Path: "/api/oauth/" + provider, MaxAge: -1})
+
+	state, verifier, ok := strings.Cut(cookie.Value, ".")
+	if !ok || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		middleware.GetLogger(r.Context()).Warn("oauth state mismatch", "provider", provider)
+		response.Fail(w, errcode.OAuthFailed)
+		return
+	}
+	code := query.Get("code")
+	if code == "" {
+		invalidParam(w, "code", "is required")
+		return
+	}
+
+	user, token, err := h.svc.Callback(r.Context(), provider, code, verifier)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	resp := map[string]interface{}{
+		"token": token,
+		"user": map[string]interface{}{
+			"id":       user.ID,
+			"nickname": user.Nickname,
+			"avatar":   user.Avatar,
+			"role":     user.Role,
+		},
+	}
+	if middleware.CookieAuthEnabled() {
+		middleware.SetTokenCookie(w, token, config.Cfg.GetJwtDuration())
+	}
+	response.Success(w, resp)
+}
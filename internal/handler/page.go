@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gngtwhh/WBlog/internal/repository"
+)
+
+// Page is a list reply with pagination metadata. A request with a
+// cursor param (empty for the first page) is served by keyset: Page and
+// Prev are omitted and NextCursor continues the list. Next and Prev are
+// links to the neighbouring pages, absent at either end.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int64  `json:"total"`
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// parseCursor reports whether r asks for keyset pagination and its
+// cursor, replying ParamError on a malformed one.
+func parseCursor(w http.ResponseWriter, r *http.Request) (cursor *repository.Cursor, keyset, ok bool) {
+	values, keyset := r.URL.Query()["cursor"]
+	if !keyset {
+		return nil, false, true
+	}
+	cursor, err := repository.ParseCursor(values[0])
+	if err != nil {
+		invalidParam(w, "cursor", "is malformed")
+		return nil, true, false
+	}
+	return cursor, true, true
+}
+
+// pageLink returns the URL of r with the query param key set to value.
+func pageLink(r *http.Request, key, value string) string {
+	q := r.URL.Query()
+	q.Set(key, value)
+	return r.URL.Path + "?" + q.Encode()
+}
+
+func offsetPage[T any](r *http.Request, items []T, total int64, page, pageSize int) Page[T] {
+	p := Page[T]{Items: items, Total: total, Page: page, PageSize: pageSize}
+	if int64(page)*int64(pageSize) < total {
+		p.Next = pageLink(r, "page", strconv.Itoa(page+1))
+	}
+	if page > 1 {
+		p.Prev = pageLink(r, "page", strconv.Itoa(page-1))
+	}
+	return p
+}
+
+// cursorPage builds a keyset page from pageSize+1 fetched items, the
+// extra one only tells there is a next page. cursorOf returns the
+// position of an item.
+func cursorPage[T any](r *http.Request, items []T, total int64, pageSize int, cursorOf func(T) *repository.Cursor) Page[T] {
+	p := Page[T]{Items: items, Total: total, PageSize: pageSize}
+	if len(items) > pageSize {
+		p.Items = items[:pageSize]
+		p.NextCursor = cursorOf(p.Items[pageSize-1]).String()
+		p.Next = pageLink(r, "cursor", p.NextCursor)
+	}
+	return p
+}
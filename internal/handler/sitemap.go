@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gngtwhh/WBlog/internal/config"
+	"github.com/gngtwhh/WBlog/internal/middleware"
+	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/internal/repository"
+	"github.com/gngtwhh/WBlog/internal/service"
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/response"
+	"github.com/gngtwhh/WBlog/pkg/sitemap"
+)
+
+// SitemapHandler serves /sitemap.xml and /robots.txt to crawlers. The
+// sitemap lists the home page and every published article; when they do
+// not fit in one, /sitemap.xml is an index of /sitemap/pages.xml and
+// /sitemap/articles-{n}.xml, part n holding the ids from (n-1)*size+1 to
+// n*size. Sitemaps need site.base_url and are 404 without it.
+type SitemapHandler struct {
+	svc *service.ArticleService
+}
+
+func NewSitemapHandler(svc *service.ArticleService) *SitemapHandler {
+	return &SitemapHandler{svc: svc}
+}
+
+// Sitemap handles GET /sitemap.xml.
+func (h *SitemapHandler) Sitemap(w http.ResponseWriter, r *http.Request) {
+	base, size := config.Cfg.GetBaseURL(), config.Cfg.GetSitemapSize()
+	if base == "" {
+		response.Fail(w, errcode.NotFound)
+		return
+	}
+
+	total, err := h.svc.Count(r.Context(), repository.ArticleQuery{Status: model.ArticlePublished})
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	if total+1 <= int64(size) {
+		articles, err := h.articleURLs(r, base, 0, 0, size-1)
+		if err != nil {
+			response.Error(w, err)
+			return
+		}
+		home := sitemap.URL{Loc: base + "/"}
+		for _, u := range articles {
+			if u.LastMod.After(home.LastMod) {
+				home.LastMod = u.LastMod
+			}
+		}
+		body, err := sitemap.URLSet(append([]sitemap.URL{home}, articles...))
+		h.write(w, r, body, err)
+		return
+	}
+
+	last, err := h.svc.LastPublishedID(r.Context())
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	parts := int((last + int64(size) - 1) / int64(size))
+	sitemaps := []sitemap.URL{{Loc: base + "/sitemap/pages.xml"}}
+	for n := 1; n <= parts; n++ {
+		sitemaps = append(sitemaps, sitemap.URL{Loc: fmt.Sprintf("%s/sitemap/articles-%d.xml", base, n)})
+	}
+	body, err := sitemap.Index(sitemaps)
+	h.write(w, r, body, err)
+}
+
+// Part handles GET /sitemap/{file}, the sitemaps listed by the index.
+func (h *SitemapHandler) Part(w http.ResponseWriter, r *http.Request) {
+	base, file := config.Cfg.GetBaseURL(), r.PathValue("file")
+	if base == "" {
+		response.Fail(w, errcode.NotFound)
+		return
+	}
+	if file == "pages.xml" {
+		newest, err := h.svc.ListArticles(r.Context(), repository.ArticleQuery{
+			Status: model.ArticlePublished, Sort: repository.SortUpdated, Compact: true, Limit: 1,
+		})
+		if err != nil {
+			response.Error(w, err)
+			return
+		}
+		home := sitemap.URL{Loc: base + "/"}
+		if len(newest) > 0 {
+			home.LastMod = newest[0].UpdatedAt
+		}
+		body, err := sitemap.URLSet([]sitemap.URL{home})
+		h.write(w, r, body, err)
+		return
+	}
+
+	num, ok := strings.CutPrefix(file, "articles-")
+	num, ok2 := strings.CutSuffix(num, ".xml")
+	n, err := strconv.Atoi(num)
+	if !ok || !ok2 || err != nil || n < 1 {
+		response.Fail(w, errcode.NotFound)
+		return
+	}
+	size := config.Cfg.GetSitemapSize()
+	from := int64(n-1) * int64(size)
+	urls, err := h.articleURLs(r, base, from, from+int64(size), size)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	if len(urls) == 0 {
+		last, err := h.svc.LastPublishedID(r.Context())
+		if err != nil {
+			response.Error(w, err)
+			return
+		}
+		// deletes may empty a part the index still lists
+		if from >= last {
+			response.Fail(w, errcode.NotFound)
+			return
+		}
+	}
+	body, err := sitemap.URLSet(urls)
+	h.write(w, r, body, err)
+}
+
+// articleURLs lists at most limit published articles with ids in
+// (after, until] by id, until 0 leaving them unbounded. New articles
+// only ever append to the last sitemap.
+func (h *SitemapHandler) articleURLs(r *http.Request, base string, after, until int64, limit int) ([]sitemap.URL, error) {
+	stamps, err := h.svc.Stamps(r.Context(), after, limit)
+	if err != nil {
+		return nil, err
+	}
+	urls := make([]sitemap.URL, 0, len(stamps))
+	for _, a := range stamps {
+		if until > 0 && int64(a.ID) > until {
+			break
+		}
+		urls = append(urls, sitemap.URL{Loc: base + "/article/" + strconv.FormatUint(a.ID, 10), LastMod: a.UpdatedAt})
+	}
+	return urls, nil
+}
+
+// write replies an encoded sitemap. Last-Modified is the article list
+// version, the unix nano time of the last article change.
+func (h *SitemapHandler) write(w http.ResponseWriter, r *http.Request, body []byte, err error) {
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	middleware.SetLastModified(w, time.Unix(0, h.svc.Version(r.Context())))
+	w.Write(body)
+}
+
+// Robots handles GET /robots.txt.
+func (h *SitemapHandler) Robots(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	for _, path := range config.Cfg.Site.RobotsAllow {
+		fmt.Fprintf(&b, "Allow: %s\n", path)
+	}
+	for _, path := range config.Cfg.GetRobotsDisallow() {
+		fmt.Fprintf(&b, "Disallow: %s\n", path)
+	}
+	if base := config.Cfg.GetBaseURL(); base != "" {
+		fmt.Fprintf(&b, "\nSitemap: %s/sitemap.xml\n", base)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
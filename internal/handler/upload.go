@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/response"
+)
+
+// readFormFile reads the multipart file in field, limited to maxSize bytes.
+// On failure the error response is already written and ok is false.
+func readFormFile(w http.ResponseWriter, r *http.Request, field string, maxSize int64) (data []byte, filename string, ok bool) {
+	// leave some room for the multipart boundaries and headers
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+64<<10)
+	if err := r.ParseMultipartForm(maxSize); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			response.Fail(w, errcode.FileTooLarge)
+			return nil, "", false
+		}
+		invalidParam(w, field, "invalid multipart form")
+		return nil, "", false
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		invalidParam(w, field, "is required")
+		return nil, "", false
+	}
+	defer file.Close()
+	if header.Size > maxSize {
+		response.Fail(w, errcode.FileTooLarge)
+		return nil, "", false
+	}
+	data, err = io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		response.Fail(w, errcode.ServerError)
+		return nil, "", false
+	}
+	if int64(len(data)) > maxSize {
+		response.Fail(w, errcode.FileTooLarge)
+		return nil, "", false
+	}
+	return data, header.Filename, true
+}
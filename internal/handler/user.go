@@ -1,11 +1,11 @@
 package handler
 
 import (
-	"encoding/json"
-	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/gngtwhh/WBlog/internal/config"
 	"github.com/gngtwhh/WBlog/internal/middleware"
 	"github.com/gngtwhh/WBlog/internal/model"
 	"github.com/gngtwhh/WBlog/internal/service"
@@ -18,25 +18,27 @@ type UserHandler struct {
 }
 
 type RegisterRequest struct {
-	Username        string `json:"username"`
-	Password        string `json:"password"`
-	ConfirmPassword string `json:"confirm_password"`
-	Nickname        string `json:"nickname"`
+	Username        string `json:"username" validate:"required,min=3,max=32,pattern=^[A-Za-z0-9_]+$"`
+	Password        string `json:"password" validate:"required,min=6,maxbytes=72"` // bcrypt uses 72 bytes at most
+	ConfirmPassword string `json:"confirm_password" validate:"required"`
+	Nickname        string `json:"nickname" validate:"max=32"`
 }
 
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username  string `json:"username" validate:"required"`
+	Password  string `json:"password" validate:"required"`
+	UseCookie bool   `json:"use_cookie"` // keep token in HttpOnly cookie instead of response body
 }
 
 type UpdateProfileRequest struct {
-	Nickname string `json:"nickname"`
-	Avatar   string `json:"avatar"`
+	Nickname string `json:"nickname" validate:"max=32"`
+	Avatar   string `json:"avatar" validate:"max=512"`
+	Locale   string `json:"locale" validate:"max=16"`
 }
 
 type ChangePasswordRequest struct {
-	OldPassword string `json:"old_password"`
-	NewPassword string `json:"new_password"`
+	OldPassword string `json:"old_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6,maxbytes=72"`
 }
 
 func NewUserHandler(svc *service.UserService) *UserHandler {
@@ -45,18 +47,11 @@ func NewUserHandler(svc *service.UserService) *UserHandler {
 
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Fail(w, errcode.ParamError, "Invalid json request body")
-		// http.Error(w, "Invalid json request body", http.StatusBadRequest)
-		return
-	}
-	if req.Username == "" || req.Password == "" {
-		response.Fail(w, errcode.ParamError, "need username and password field")
-		// http.Error(w, "need username and password field", http.StatusBadRequest)
+	if !bind(w, r, &req) {
 		return
 	}
 	if req.Password != req.ConfirmPassword {
-		response.Fail(w, errcode.ParamError, "the passwords entered twice must be consistent.")
+		invalidParam(w, "confirm_password", "must match password")
 		// http.Error(w, "the passwords entered twice must be consistent.", http.StatusBadRequest)
 		return
 	}
@@ -66,12 +61,8 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		Password: req.Password,
 		Nickname: req.Nickname,
 	}
-	if err := h.svc.Register(&user); err != nil {
-		if errors.Is(err, service.ErrUserExists) {
-			response.Fail(w, errcode.UserExists)
-			return
-		}
-		response.Fail(w, errcode.ServerError)
+	if err := h.svc.Register(r.Context(), &user); err != nil {
+		response.Error(w, err)
 		return
 	}
 	response.Success(w, map[string]interface{}{
@@ -81,17 +72,12 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid json request body", http.StatusBadRequest)
+	if !bind(w, r, &req) {
 		return
 	}
-	user, token, err := h.svc.Login(req.Username, req.Password)
+	user, token, err := h.svc.Login(r.Context(), req.Username, req.Password)
 	if err != nil {
-		if errors.Is(err, service.ErrAuthFailed) {
-			response.Fail(w, errcode.AuthFailed)
-			return
-		}
-		response.Fail(w, errcode.ServerError)
+		response.Error(w, err)
 		return
 	}
 	resp := map[string]interface{}{
@@ -103,6 +89,10 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 			"role":     user.Role,
 		},
 	}
+	if req.UseCookie && middleware.CookieAuthEnabled() {
+		middleware.SetTokenCookie(w, token, config.Cfg.GetJwtDuration())
+		delete(resp, "token")
+	}
 	response.Success(w, resp)
 }
 
@@ -110,14 +100,11 @@ func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r)
 	if !ok {
 		response.Fail(w, errcode.TokenInvalid)
+		return
 	}
-	user, err := h.svc.GetProfile(userID)
+	user, err := h.svc.GetProfile(r.Context(), userID)
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
-			response.Fail(w, errcode.UserNotFound)
-			return
-		}
-		response.Fail(w, errcode.ServerError)
+		response.Error(w, err)
 		return
 	}
 	response.Success(w, user)
@@ -131,8 +118,7 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req UpdateProfileRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Fail(w, errcode.ParamError, "Invalid json request body")
+	if !bind(w, r, &req) {
 		return
 	}
 
@@ -140,17 +126,35 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		ID:       userID,
 		Nickname: req.Nickname,
 		Avatar:   req.Avatar,
+		Locale:   req.Locale,
 	}
 
-	if err := h.svc.UpdateProfile(user); err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
-			response.Fail(w, errcode.UserNotFound)
-			return
-		}
-		response.Fail(w, errcode.ServerError)
+	if err := h.svc.UpdateProfile(r.Context(), user); err != nil {
+		response.Error(w, err)
 		return
 	}
-	response.Success(w, nil)
+	if req.Locale == "" {
+		response.Success(w, nil)
+		return
+	}
+
+	// the locale is read from the token, hand out one carrying the new
+	// locale, expiring with the current one
+	expires := config.Cfg.GetJwtDuration()
+	if exp, ok := middleware.GetClaimsExp(r); ok {
+		expires = time.Until(time.Unix(exp, 0))
+	}
+	token, err := h.svc.ReissueToken(r.Context(), userID, expires)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	if middleware.IsCookieAuth(r) {
+		middleware.SetTokenCookie(w, token, expires)
+		response.Success(w, nil)
+		return
+	}
+	response.Success(w, map[string]string{"token": token})
 }
 
 func (h *UserHandler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
@@ -160,56 +164,63 @@ func (h *UserHandler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req ChangePasswordRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Fail(w, errcode.ParamError, "Invalid json request body")
-		return
-	}
-	if err := h.svc.ChangePassword(userID, req.OldPassword, req.NewPassword); err != nil {
-		if errors.Is(err, service.ErrInvalidOldPass) {
-			response.Fail(w, errcode.AuthFailed, "Old password incorrect")
-			return
-		}
-		if errors.Is(err, service.ErrUserNotFound) {
-			response.Fail(w, errcode.UserNotFound)
-			return
-		}
-		response.Fail(w, errcode.ServerError)
+	if !bind(w, r, &req) {
+		return
+	}
+	if err := h.svc.ChangePassword(r.Context(), userID, req.OldPassword, req.NewPassword); err != nil {
+		response.Error(w, err)
 		return
 	}
 	response.Success(w, nil)
 }
 
+// UploadAvatar handles a multipart POST with the image in field "avatar".
+// Only PNG/JPEG/GIF/WebP detected from content are accepted.
 func (h *UserHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
-	// TODO: need implement
-	response.Fail(w, errcode.ServerError, "feature not implemented yet")
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		response.Fail(w, errcode.TokenInvalid)
+		return
+	}
+
+	data, _, ok := readFormFile(w, r, "avatar", config.Cfg.GetMaxAvatarSize())
+	if !ok {
+		return
+	}
+
+	avatar, err := h.svc.UploadAvatar(r.Context(), userID, data)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, map[string]string{"avatar": avatar})
 }
 
 func (h *UserHandler) GetUserInfo(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		response.Fail(w, errcode.ParamError, "invalid user id")
+		invalidParam(w, "id", "must be an integer")
 		return
 	}
 
-	user, err := h.svc.GetProfile(uint64(id))
+	user, err := h.svc.GetProfile(r.Context(), uint64(id))
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
-			response.Fail(w, errcode.UserNotFound)
-			return
-		}
-		response.Fail(w, errcode.ServerError)
+		response.Error(w, err)
 		return
 	}
 
-	publicInfo := map[string]interface{}{
+	response.Success(w, publicProfile(user))
+}
+
+// publicProfile is the part of user anyone may see.
+func publicProfile(user *model.User) map[string]interface{} {
+	return map[string]interface{}{
 		"id":         user.ID,
 		"nickname":   user.Nickname,
 		"avatar":     user.Avatar,
 		"created_at": user.CreatedAt,
 	}
-
-	response.Success(w, publicInfo)
 }
 
 func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
@@ -225,11 +236,11 @@ func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.svc.Logout(tokenStr, exp); err != nil {
+	if err := h.svc.Logout(r.Context(), tokenStr, exp); err != nil {
 		response.Fail(w, errcode.ServerError)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{Name: "token", MaxAge: -1, Path: "/"})
+	middleware.ClearTokenCookie(w)
 	response.Success(w, nil)
 }
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gngtwhh/WBlog/internal/middleware"
+	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/internal/service"
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/response"
+)
+
+// Handlers of the resource-oriented /api/v2 routes. They share the
+// services with v1, ids come from the path instead of the query string.
+
+// PatchArticleRequest binds a PATCH body, absent fields are left unchanged.
+type PatchArticleRequest struct {
+	Title    *string `json:"title" validate:"min=1,max=200"`
+	Author   *string `json:"author" validate:"max=64"`
+	Content  *string `json:"content" validate:"min=1"`
+	Abstract *string `json:"abstract" validate:"max=500"`
+	Status   *string `json:"status" validate:"enum=published|draft"`
+}
+
+// CreateCommentV2Request binds POST /api/v2/articles/{id}/comments.
+type CreateCommentV2Request struct {
+	Content string `json:"content" validate:"required,max=2000"`
+}
+
+// pathID parses the {id} wildcard, replying ParamError if it is not a
+// positive integer.
+func pathID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		invalidParam(w, "id", "must be a positive integer")
+		return 0, false
+	}
+	return id, true
+}
+
+// ListV2 handles GET /api/v2/articles.
+// Query params page, page_size, cursor and those of ListArticlesQuery
+// are optional.
+func (h *ArticleHandler) ListV2(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := pageParams(r)
+	h.list(w, r, page, pageSize)
+}
+
+// GetV2 handles GET /api/v2/articles/{id}.
+func (h *ArticleHandler) GetV2(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	article, err := h.svc.GetArticle(r.Context(), id)
+	if err == nil && !visible(r, article) {
+		err = service.ErrArticleNotFound
+	}
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	// no Last-Modified, view_count and comment_count change without
+	// updated_at; the ETag of the body covers them
+	response.Success(w, article)
+}
+
+// CreateV2 handles POST /api/v2/articles, replying 201 with the article.
+func (h *ArticleHandler) CreateV2(w http.ResponseWriter, r *http.Request) {
+	var req CreateArticleRequest
+	if !bind(w, r, &req) {
+		return
+	}
+
+	article := model.Article{
+		Title:    req.Title,
+		Author:   req.Author,
+		Content:  req.Content,
+		Abstract: req.Abstract,
+		Status:   req.Status,
+	}
+	if err := h.svc.Create(r.Context(), &article); err != nil {
+		response.Error(w, err)
+		return
+	}
+	created, err := h.svc.GetArticle(r.Context(), int64(article.ID))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Created(w, fmt.Sprintf("/api/v2/articles/%d", article.ID), created)
+}
+
+// PatchV2 handles PATCH /api/v2/articles/{id}, updating only the fields
+// present in the body.
+func (h *ArticleHandler) PatchV2(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req PatchArticleRequest
+	if !bind(w, r, &req) {
+		return
+	}
+
+	article, err := h.svc.Patch(r.Context(), id, service.ArticlePatch{
+		Title:    req.Title,
+		Author:   req.Author,
+		Content:  req.Content,
+		Abstract: req.Abstract,
+		Status:   req.Status,
+	})
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, article)
+}
+
+// DeleteV2 handles DELETE /api/v2/articles/{id}.
+func (h *ArticleHandler) DeleteV2(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(r.Context(), id); err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, nil)
+}
+
+// ListV2 handles GET /api/v2/articles/{id}/comments.
+// Query params page, page_size and cursor are optional.
+func (h *CommentHandler) ListV2(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	page, pageSize := pageParams(r)
+	h.list(w, r, id, page, pageSize)
+}
+
+// CreateV2 handles POST /api/v2/articles/{id}/comments, replying 201.
+func (h *CommentHandler) CreateV2(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		response.Fail(w, errcode.TokenInvalid)
+		return
+	}
+	articleID, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req CreateCommentV2Request
+	if !bind(w, r, &req) {
+		return
+	}
+	if !h.commentable(w, r, articleID) {
+		return
+	}
+
+	username, _ := middleware.GetUsername(r)
+	comment := &model.Comment{
+		UserID:    userID,
+		ArticleID: uint64(articleID),
+		Username:  username,
+		Content:   req.Content,
+	}
+	if err := h.commentsvc.Create(r.Context(), comment); err != nil {
+		response.Error(w, err)
+		return
+	}
+	h.articlesvc.Refresh(r.Context(), articleID)
+	// comments have no route of their own yet
+	response.Created(w, fmt.Sprintf("/api/v2/articles/%d/comments", articleID),
+		map[string]uint64{"id": comment.ID})
+}
+
+// GetV2 handles GET /api/v2/users/{id}, replying the public profile.
+func (h *UserHandler) GetV2(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	user, err := h.svc.GetProfile(r.Context(), uint64(id))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	response.Success(w, publicProfile(user))
+}
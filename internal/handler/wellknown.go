@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gngtwhh/WBlog/pkg/utils"
+)
+
+// WellKnownHandler serves the /.well-known/ documents.
+type WellKnownHandler struct{}
+
+func NewWellKnownHandler() *WellKnownHandler {
+	return &WellKnownHandler{}
+}
+
+// JWKS returns the public keys used to verify WBlog tokens.
+// It is a standard JWK Set, not wrapped in the response envelope.
+func (h *WellKnownHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string][]utils.JWK{"keys": utils.JWKS()})
+}
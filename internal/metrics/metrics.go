@@ -0,0 +1,103 @@
+// Package metrics holds the Prometheus collectors of WBlog and serves them
+// in the text exposition format.
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wblog_http_requests_total",
+		Help: "HTTP requests by route pattern, method and status.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "wblog_http_request_duration_seconds",
+		Help:    "HTTP request latency by route pattern and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	ErrCodes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wblog_errcode_total",
+		Help: "Failed responses by business errcode.",
+	}, []string{"code"})
+
+	Panics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wblog_http_panics_total",
+		Help: "Handler panics recovered, by route pattern.",
+	}, []string{"route"})
+
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wblog_cache_requests_total",
+		Help: "Service cache lookups by cache and result (hit, miss, error).",
+	}, []string{"cache", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		HTTPRequests, HTTPDuration, ErrCodes, Panics, CacheRequests,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDB exports the connection pool stats of db.
+func RegisterDB(db *sql.DB, name string) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveErrCode counts a failed response.
+func ObserveErrCode(code int) {
+	ErrCodes.WithLabelValues(strconv.Itoa(code)).Inc()
+}
+
+// ReportPanic counts a recovered panic, it can be used as a
+// middleware.PanicSink.
+func ReportPanic(r *http.Request, recovered any, stack []byte) {
+	route := r.Pattern
+	if route == "" {
+		route = "unmatched"
+	}
+	Panics.WithLabelValues(route).Inc()
+}
+
+// ObserveCache counts a cache lookup, err is the lookup error other than a miss.
+func ObserveCache(name string, hit bool, err error) {
+	result := "miss"
+	switch {
+	case err != nil:
+		result = "error"
+	case hit:
+		result = "hit"
+	}
+	CacheRequests.WithLabelValues(name, result).Inc()
+}
+
+// Handler serves the registry. A non-empty token must be presented as
+// "Authorization: Bearer <token>".
+func Handler(token string) http.Handler {
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/gngtwhh/WBlog/internal/cache"
+	"github.com/gngtwhh/WBlog/internal/model"
 	"github.com/gngtwhh/WBlog/pkg/errcode"
 	"github.com/gngtwhh/WBlog/pkg/response"
 	"github.com/gngtwhh/WBlog/pkg/utils"
@@ -18,48 +19,103 @@ const (
 	UsernameKey  ContextKey = "username"
 	TokenRawKey  ContextKey = "token_raw"
 	ClaimsExpKey ContextKey = "claims_exp"
+	ByCookieKey  ContextKey = "by_cookie"
+	RoleKey      ContextKey = "role"
 )
 
-func Auth(next http.HandlerFunc) http.HandlerFunc {
+// BlacklistPolicy decides what happens when revoked tokens can not be
+// checked reliably, e.g. the cache is down or running on a local fallback.
+type BlacklistPolicy int
+
+const (
+	FailOpen   BlacklistPolicy = iota // accept the token, availability first
+	FailClosed                        // reject the request, security first
+)
+
+// Auth returns a middleware that authenticates requests by JWT,
+// revoked tokens are looked up in store.
+func Auth(store cache.Store, policy BlacklistPolicy) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return authHandler(store, policy, next)
+	}
+}
+
+func authHandler(store cache.Store, policy BlacklistPolicy, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		var tokenStr string
+		byCookie := false
 		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			response.Fail(w, errcode.AuthFailed, "unauthorized request: missing token")
-			// http.Error(w, "unauthorized request: missing token", http.StatusUnauthorized)
-			return
-		}
-		// get token
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			response.Fail(w, errcode.AuthFailed, "unauthorized request: the token format is incorrect")
+		if authHeader != "" {
+			// get token
+			parts := strings.SplitN(authHeader, " ", 2)
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				response.Fail(w, errcode.Unauthorized)
+				return
+			}
+			tokenStr = parts[1]
+		} else if cookie, err := r.Cookie(session.CookieName); session.CookieAuth && err == nil && cookie.Value != "" {
+			tokenStr = cookie.Value
+			byCookie = true
+		} else {
+			response.Fail(w, errcode.Unauthorized)
 			return
 		}
-		tokenStr := parts[1]
 
 		// check blacklist
 		key := cache.PrefixJWTBlacklist + tokenStr
-		n, err := cache.RDB.Exists(context.Background(), key).Result()
-		if err == nil && n > 0 {
-			response.Fail(w, errcode.AuthFailed, "unauthorized request: login has expired, please log in again")
+		revoked, err := store.Exists(r.Context(), key)
+		if err == nil && revoked {
+			response.Fail(w, errcode.TokenInvalid)
 			return
 		}
+		if err != nil || cache.Degraded(store) {
+			if policy == FailClosed {
+				GetLogger(r.Context()).Warn("token blacklist unavailable, request rejected", "err", err)
+				response.Fail(w, errcode.Unavailable)
+				return
+			}
+			if err != nil {
+				GetLogger(r.Context()).Warn("token blacklist unavailable, token accepted", "err", err)
+			}
+		}
 
 		claims, err := utils.ParseToken(tokenStr)
 		if err != nil {
-			response.Fail(w, errcode.AuthFailed, "unauthorized request: invalid or expired token")
+			response.Fail(w, errcode.TokenInvalid)
 			return
 		}
 
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 		ctx = context.WithValue(ctx, UsernameKey, claims.Username)
+		ctx = context.WithValue(ctx, RoleKey, claims.Role)
 		ctx = context.WithValue(ctx, TokenRawKey, tokenStr)
+		ctx = context.WithValue(ctx, ByCookieKey, byCookie)
 		if claims.ExpiresAt != nil {
 			ctx = context.WithValue(ctx, ClaimsExpKey, claims.ExpiresAt.Unix())
 		}
+		setUserLocale(w, claims.Locale)
 		next(w, r.WithContext(ctx))
 	}
 }
 
+// AdminOnly must be wrapped by Auth, it rejects non-admin users.
+func AdminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r) {
+			response.Fail(w, errcode.Forbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// IsAdmin reports whether r was authenticated by an admin. It is false
+// on routes without Auth.
+func IsAdmin(r *http.Request) bool {
+	role, ok := GetRole(r)
+	return ok && role == model.RoleAdmin
+}
+
 func GetUserID(r *http.Request) (uint64, bool) {
 	id, ok := r.Context().Value(UserIDKey).(uint64)
 	return id, ok
@@ -70,6 +126,11 @@ func GetUsername(r *http.Request) (string, bool) {
 	return username, ok
 }
 
+func GetRole(r *http.Request) (int, bool) {
+	role, ok := r.Context().Value(RoleKey).(int)
+	return role, ok
+}
+
 func GetTokenRaw(r *http.Request) (string, bool) {
 	token, ok := r.Context().Value(TokenRawKey).(string)
 	return token, ok
@@ -79,3 +140,9 @@ func GetClaimsExp(r *http.Request) (int64, bool) {
 	exp, ok := r.Context().Value(ClaimsExpKey).(int64)
 	return exp, ok
 }
+
+// IsCookieAuth reports whether the request was authenticated by the token cookie.
+func IsCookieAuth(r *http.Request) bool {
+	byCookie, _ := r.Context().Value(ByCookieKey).(bool)
+	return byCookie
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gngtwhh/WBlog/internal/cache"
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+)
+
+// downStore is a cache whose backend can not be reached.
+type downStore struct{}
+
+var errDown = errors.New("connection refused")
+
+func (downStore) Get(context.Context, string) (string, error)                { return "", errDown }
+func (downStore) Set(context.Context, string, string, time.Duration) error   { return errDown }
+func (downStore) Del(context.Context, ...string) error                       { return errDown }
+func (downStore) Exists(context.Context, string) (bool, error)               { return false, errDown }
+func (downStore) Incr(context.Context, string, time.Duration) (int64, error) { return 0, errDown }
+func (downStore) Close() error                                               { return nil }
+
+func TestAuthFailClosedBeforeBreakerOpens(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := cache.NewBreakerStore(downStore{}, cache.NewLRUStore(10), cache.BreakerOptions{FailureThreshold: 100}, logger)
+	reached := false
+	h := authHandler(store, FailClosed, func(w http.ResponseWriter, r *http.Request) { reached = true })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/profile", nil)
+	req.Header.Set("Authorization", "Bearer revoked")
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	if cache.Degraded(store) {
+		t.Fatal("breaker should still be closed")
+	}
+	var body struct {
+		Code int `json:"code"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if reached || body.Code != errcode.Unavailable {
+		t.Errorf("reached = %v, code = %d; want the request rejected with %d", reached, body.Code, errcode.Unavailable)
+	}
+}
@@ -0,0 +1,244 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encoder is implemented by gzip, brotli and zstd writers.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+type zstdEncoder struct{ *zstd.Encoder }
+
+func (z zstdEncoder) Reset(w io.Writer) { z.Encoder.Reset(w) }
+
+// encodings in server preference order, used to break q-value ties.
+var encodings = []string{"br", "zstd", "gzip"}
+
+var encoderPools = map[string]*sync.Pool{
+	"br": {New: func() any { return brotli.NewWriterLevel(nil, 5) }},
+	"zstd": {New: func() any {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return zstdEncoder{enc}
+	}},
+	"gzip": {New: func() any {
+		gw, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return gw
+	}},
+}
+
+// negotiateEncoding picks the accepted encoding with the highest q-value,
+// or "" if the client accepts none we support.
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	q := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		weight := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				weight = f
+			}
+		}
+		q[name] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range encodings {
+		w, ok := q[enc]
+		if !ok {
+			w, ok = q["*"]
+		}
+		if ok && w > bestQ {
+			best, bestQ = enc, w
+		}
+	}
+	return best
+}
+
+// compressible reports whether a content type benefits from compression.
+// Images, archives and other already-compressed payloads are skipped.
+func compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mt, "text/") || strings.HasSuffix(mt, "+json") || strings.HasSuffix(mt, "+xml") {
+		return true
+	}
+	switch mt {
+	case "application/json", "application/javascript", "application/xml",
+		"image/svg+xml", "application/wasm":
+		return true
+	}
+	return false
+}
+
+// compressWriter buffers the first minSize bytes to decide whether the body
+// is worth compressing, then streams through the negotiated encoder.
+type compressWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	encoding string
+	minSize  int
+
+	statusCode int
+	buf        []byte
+	decided    bool
+	enc        encoder
+	rawSize    int64
+}
+
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.decided || cw.statusCode != 0 {
+		return
+	}
+	if statusCode < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	cw.statusCode = statusCode
+	// no body follows, pass the header straight through
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified || cw.r.Method == http.MethodHead {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.statusCode == 0 {
+		cw.statusCode = http.StatusOK
+	}
+	cw.rawSize += int64(len(b))
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.minSize {
+			return len(b), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// decide writes the header and buffered bytes, compressing when the body
+// is large enough and of a compressible type.
+func (cw *compressWriter) decide(large bool) error {
+	cw.decided = true
+	h := cw.Header()
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	eligible := h.Get("Content-Encoding") == "" && h.Get("Content-Range") == "" &&
+		cw.statusCode != http.StatusPartialContent && compressible(h.Get("Content-Type"))
+	if eligible || cw.statusCode == http.StatusNotModified {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if eligible && large && cw.encoding != "" {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		cw.enc = encoderPools[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.enc != nil {
+		_, err := cw.enc.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// Flush sends what has been written so far, compressing it if eligible.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.statusCode == 0 {
+			cw.statusCode = http.StatusOK
+		}
+		cw.decide(true)
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close flushes small buffered bodies, finishes the encoder and reports
+// the uncompressed size to the request logger.
+func (cw *compressWriter) close() {
+	if !cw.decided && cw.statusCode != 0 {
+		cw.decide(false)
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+		encoderPools[cw.encoding].Put(cw.enc)
+		if lw := findLogWriter(cw.ResponseWriter); lw != nil {
+			lw.encoding = cw.encoding
+			lw.rawSize = cw.rawSize
+		}
+		cw.enc = nil
+	}
+}
+
+// Compress negotiates br, zstd or gzip from Accept-Encoding and compresses
+// text-like responses of at least minSize bytes.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cw := &compressWriter{
+				ResponseWriter: w,
+				r:              r,
+				encoding:       negotiateEncoding(r.Header.Get("Accept-Encoding")),
+				minSize:        minSize,
+			}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// findLogWriter walks the Unwrap chain to the RequestLogger writer, other
+// middleware such as Recovery may wrap it.
+func findLogWriter(w http.ResponseWriter) *logResponseWriter {
+	for {
+		switch rw := w.(type) {
+		case *logResponseWriter:
+			return rw
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := map[string]string{
+		"":                          "",
+		"gzip":                      "gzip",
+		"gzip, deflate, br":         "br",
+		"gzip;q=1.0, br;q=0.5":      "gzip",
+		"br;q=0, zstd":              "zstd",
+		"*":                         "br",
+		"identity":                  "",
+		"deflate, gzip;q=0":         "",
+		"zstd;q=0.8, gzip;q=0.8, *": "br",
+	}
+	for header, want := range cases {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"title":"hello"}`, 200)
+	serve := func(contentType, body string) http.Handler {
+		return Compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			io.WriteString(w, body)
+		}))
+	}
+	get := func(h http.Handler) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get(serve("application/json", large))
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("headers = %v, want gzip with Vary", rec.Header())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != large {
+		t.Errorf("decompressed body mismatch")
+	}
+
+	rec = get(serve("application/json", `{"code":0}`))
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != `{"code":0}` {
+		t.Errorf("small body should be sent as is, got %v %q", rec.Header(), rec.Body.String())
+	}
+
+	rec = get(serve("image/png", large))
+	if rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("Vary") != "" {
+		t.Errorf("image should not be compressed, got %v", rec.Header())
+	}
+}
+
+func TestCompressLogsSizes(t *testing.T) {
+	large := strings.Repeat("<p>hello</p>", 500)
+	lw := &logResponseWriter{ResponseWriter: httptest.NewRecorder()}
+	h := Compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, large)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	h.ServeHTTP(lw, req)
+
+	if lw.encoding != "br" || lw.rawSize != int64(len(large)) {
+		t.Fatalf("encoding = %q, raw = %d", lw.encoding, lw.rawSize)
+	}
+	if lw.size == 0 || lw.size >= lw.rawSize {
+		t.Errorf("wire size = %d, raw = %d", lw.size, lw.rawSize)
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gngtwhh/WBlog/pkg/i18n"
+	"github.com/gngtwhh/WBlog/pkg/response"
+)
+
+// Language negotiates the locale of the response from Accept-Language,
+// package response localizes msg with it. Headers are left to the
+// localized replies, static files and feeds must not vary by language.
+func Language(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lw := &response.LocaleWriter{ResponseWriter: w, Locale: i18n.Negotiate(r.Header.Get("Accept-Language"))}
+		next.ServeHTTP(lw, r)
+	})
+}
+
+// setUserLocale lets the preference of an authenticated user override
+// the negotiated locale.
+func setUserLocale(w http.ResponseWriter, locale string) {
+	if l, ok := i18n.Supported(locale); ok {
+		response.SetLocale(w, l)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"time"
 	// 假设你有一个简单的生成随机字符串的工具，如果没有，暂时用 time.Now().UnixNano() 代替
 	// "github.com/google/uuid"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type contextKey string
@@ -18,6 +20,10 @@ const LoggerKey contextKey = "logger"
 type logResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	size       int64 // bytes on the wire
+	// set by Compress when the body was encoded
+	encoding string
+	rawSize  int64
 }
 
 // WriteHeader intercepts ResponseWriter.WriteHeader
@@ -31,7 +37,13 @@ func (rw *logResponseWriter) Write(b []byte) (int, error) {
 	if rw.statusCode == 0 {
 		rw.statusCode = http.StatusOK
 	}
-	return rw.ResponseWriter.Write(b)
+	n, err := rw.ResponseWriter.Write(b)
+	rw.size += int64(n)
+	return n, err
+}
+
+func (rw *logResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
 }
 
 func genShortID() string {
@@ -40,18 +52,29 @@ func genShortID() string {
 	return hex.EncodeToString(bytes)
 }
 
-// RequestLogger record request log and inject Request-ID
-func RequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+// RequestLogger record request log and inject Request-ID, successful
+// requests to quiet paths are not logged
+func RequestLogger(logger *slog.Logger, quiet ...string) func(http.Handler) http.Handler {
+	quietPaths := make(map[string]bool, len(quiet))
+	for _, p := range quiet {
+		quietPaths[p] = true
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 			// reqID := time.Now().Format("20060102150405.000000")
 			reqID := genShortID()
 
-			// child logger
+			// child logger, correlated with the trace when there is one
 			reqLogger := logger.With(
 				slog.String("req_id", reqID),
 			)
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				reqLogger = reqLogger.With(
+					slog.String("trace_id", sc.TraceID().String()),
+					slog.String("span_id", sc.SpanID().String()),
+				)
+			}
 			ctx := context.WithValue(r.Context(), LoggerKey, reqLogger)
 
 			// Encapsulated ResponseWriter
@@ -61,14 +84,25 @@ func RequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
 			}
 			next.ServeHTTP(mRespWriter, r.WithContext(ctx))
 
+			if quietPaths[r.URL.Path] && mRespWriter.statusCode < http.StatusBadRequest {
+				return
+			}
 			duration := time.Since(start)
-			reqLogger.Info("HTTP",
+			attrs := []any{
 				slog.String("method", r.Method),
 				slog.Int("CODE", mRespWriter.statusCode),
 				slog.String("URL", r.URL.Path),
 				// slog.String("ip", r.RemoteAddr),
 				slog.Int64("TIME", duration.Milliseconds()),
-			)
+				slog.Int64("SIZE", mRespWriter.size),
+			}
+			if mRespWriter.encoding != "" {
+				attrs = append(attrs,
+					slog.Int64("RAW_SIZE", mRespWriter.rawSize),
+					slog.String("ENCODING", mRespWriter.encoding),
+				)
+			}
+			reqLogger.Info("HTTP", attrs...)
 		})
 	}
 }
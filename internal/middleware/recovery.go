@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/response"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PanicSink receives recovered panics, e.g. to forward them to an error
+// tracker. It must not panic itself.
+type PanicSink func(r *http.Request, recovered any, stack []byte)
+
+// Recovery turns a handler panic into a ServerError reply and logs the
+// stack through the request logger, so it must run inside RequestLogger.
+// http.ErrAbortHandler is re-panicked, as net/http expects.
+func Recovery(sinks ...PanicSink) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sw := &statusWriter{ResponseWriter: w}
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				stack := debug.Stack()
+				GetLogger(r.Context()).Error("panic recovered",
+					slog.String("method", r.Method),
+					slog.String("URL", r.URL.Path),
+					slog.Any("panic", rec),
+					slog.String("stack", string(stack)),
+				)
+				span := trace.SpanFromContext(r.Context())
+				span.RecordError(fmt.Errorf("panic: %v", rec), trace.WithStackTrace(true))
+				span.SetStatus(codes.Error, "panic")
+				for _, sink := range sinks {
+					sink(r, rec, stack)
+				}
+
+				// too late to reply once the header is out
+				if sw.statusCode == 0 {
+					response.Fail(sw, errcode.ServerError)
+				}
+			}()
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
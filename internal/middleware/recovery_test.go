@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+)
+
+func TestRecovery(t *testing.T) {
+	var reported any
+	sink := func(r *http.Request, recovered any, stack []byte) { reported = recovered }
+	h := Recovery(sink)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]int
+		m["boom"]++ // nil map write
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var body struct{ Code int }
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != errcode.ServerError {
+		t.Fatalf("body = %q, want ServerError envelope", rec.Body.String())
+	}
+	if reported == nil {
+		t.Error("sink was not called")
+	}
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	h := Recovery()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Fatalf("recovered %v, want ErrAbortHandler re-panicked", rec)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/response"
+)
+
+// SessionOptions configures the token cookie and the CSRF protection.
+type SessionOptions struct {
+	CookieAuth     bool // accept and set the token cookie
+	CookieName     string
+	Secure         bool
+	SameSite       http.SameSite
+	CSRFCookieName string
+	CSRFHeader     string
+}
+
+var session = SessionOptions{
+	CookieName:     "token",
+	SameSite:       http.SameSiteLaxMode,
+	CSRFCookieName: "csrf_token",
+	CSRFHeader:     "X-CSRF-Token",
+}
+
+// InitSession sets session options, empty names keep their default.
+func InitSession(opts SessionOptions) {
+	if opts.CookieName == "" {
+		opts.CookieName = session.CookieName
+	}
+	if opts.CSRFCookieName == "" {
+		opts.CSRFCookieName = session.CSRFCookieName
+	}
+	if opts.CSRFHeader == "" {
+		opts.CSRFHeader = session.CSRFHeader
+	}
+	if opts.SameSite == 0 {
+		opts.SameSite = http.SameSiteLaxMode
+	}
+	session = opts
+}
+
+// CookieAuthEnabled reports whether login may set the token cookie.
+func CookieAuthEnabled() bool {
+	return session.CookieAuth
+}
+
+// SetTokenCookie stores the token in an HttpOnly cookie and issues a fresh
+// CSRF token, which the front end must echo in the CSRF header.
+func SetTokenCookie(w http.ResponseWriter, token string, maxAge time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     session.CookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   session.Secure,
+		SameSite: session.SameSite,
+	})
+	setCSRFCookie(w, genCSRFToken())
+}
+
+// ClearTokenCookie removes the token cookie and its CSRF token.
+func ClearTokenCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     session.CookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   session.Secure,
+		SameSite: session.SameSite,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     session.CSRFCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   session.Secure,
+		SameSite: session.SameSite,
+	})
+}
+
+func setCSRFCookie(w http.ResponseWriter, token string) {
+	// readable by JS on purpose: double-submit pattern
+	http.SetCookie(w, &http.Cookie{
+		Name:     session.CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		Secure:   session.Secure,
+		SameSite: session.SameSite,
+	})
+}
+
+func genCSRFToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// CSRF implements double-submit cookie protection. It only applies to
+// state-changing requests authenticated by the token cookie, requests
+// carrying an Authorization header can not be forged cross-site. The
+// CSRF token is issued with the token cookie, here it is only reissued
+// to cookie sessions that lost it; other responses stay cookie free and
+// cacheable.
+func CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !session.CookieAuth {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, err := r.Cookie(session.CookieName); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		csrfCookie, err := r.Cookie(session.CSRFCookieName)
+		if err != nil || csrfCookie.Value == "" {
+			csrfCookie = &http.Cookie{Value: genCSRFToken()}
+			setCSRFCookie(w, csrfCookie.Value)
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := r.Header.Get(session.CSRFHeader)
+		if header == "" || subtle.ConstantTimeCompare([]byte(header), []byte(csrfCookie.Value)) != 1 {
+			GetLogger(r.Context()).Warn("csrf token mismatch", "path", r.URL.Path)
+			response.Fail(w, errcode.CSRFInvalid)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	InitSession(SessionOptions{CookieAuth: true})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	h := CSRF(ok)
+
+	tests := []struct {
+		name    string
+		method  string
+		cookies []*http.Cookie
+		header  http.Header
+		passed  bool
+	}{
+		{"safe method", http.MethodGet, []*http.Cookie{{Name: "token", Value: "t"}}, nil, true},
+		{"no session cookie", http.MethodPost, nil, nil, true},
+		{"bearer token", http.MethodPost, []*http.Cookie{{Name: "token", Value: "t"}},
+			http.Header{"Authorization": {"Bearer t"}}, true},
+		{"cookie without csrf header", http.MethodPost,
+			[]*http.Cookie{{Name: "token", Value: "t"}, {Name: "csrf_token", Value: "abc"}}, nil, false},
+		{"cookie with wrong csrf header", http.MethodDelete,
+			[]*http.Cookie{{Name: "token", Value: "t"}, {Name: "csrf_token", Value: "abc"}},
+			http.Header{"X-Csrf-Token": {"xyz"}}, false},
+		{"cookie with matching csrf header", http.MethodPost,
+			[]*http.Cookie{{Name: "token", Value: "t"}, {Name: "csrf_token", Value: "abc"}},
+			http.Header{"X-Csrf-Token": {"abc"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/create-comment", nil)
+			for _, c := range tt.cookies {
+				req.AddCookie(c)
+			}
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if passed := rec.Code == http.StatusNoContent; passed != tt.passed {
+				t.Errorf("passed = %v, want %v", passed, tt.passed)
+			}
+		})
+	}
+}
+
+// TestCSRFCookieScope checks the CSRF token is only handed out to cookie
+// sessions, and dropped with the token cookie.
+func TestCSRFCookieScope(t *testing.T) {
+	InitSession(SessionOptions{CookieAuth: true})
+	h := CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+	if c := rec.Header().Get("Set-Cookie"); c != "" {
+		t.Errorf("request without session got Set-Cookie %q", c)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "token", Value: "t"})
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if c := rec.Result().Cookies(); len(c) != 1 || c[0].Name != "csrf_token" {
+		t.Errorf("cookie session without csrf token got cookies %v, want a csrf_token", c)
+	}
+
+	rec = httptest.NewRecorder()
+	ClearTokenCookie(rec)
+	cleared := map[string]bool{}
+	for _, c := range rec.Result().Cookies() {
+		cleared[c.Name] = c.MaxAge < 0
+	}
+	if !cleared["token"] || !cleared["csrf_token"] {
+		t.Errorf("ClearTokenCookie expired %v, want token and csrf_token", cleared)
+	}
+}
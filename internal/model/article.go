@@ -2,6 +2,19 @@ package model
 
 import "time"
 
+// Article status, only published articles are shown to readers.
+const (
+	ArticlePublished = "published"
+	ArticleDraft     = "draft"
+)
+
+// ArticleStamp is the id and last change of an article, all a sitemap
+// needs of it.
+type ArticleStamp struct {
+	ID        uint64
+	UpdatedAt time.Time
+}
+
 // Article represents a blog article.
 type Article struct {
 	ID uint64 `json:"id"`
@@ -10,8 +23,10 @@ type Article struct {
 	Author   string `json:"author"`
 	Content  string `json:"content"`
 	Abstract string `json:"abstract"`
+	Status   string `json:"status"`
 
-	ViewCount uint64    `json:"view_count"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ViewCount    uint64    `json:"view_count"`
+	CommentCount uint64    `json:"comment_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// UserIdentity links an external OAuth2/OIDC account to a local user.
+type UserIdentity struct {
+	ID       uint64 `json:"id"`
+	UserID   uint64 `json:"user_id"`
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"` // unique per provider
+	Email    string `json:"email"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Media is an uploaded image or attachment, deduplicated by content hash.
+type Media struct {
+	ID         uint64 `json:"id"`
+	Hash       string `json:"hash"` // sha256 of content, unique
+	Filename   string `json:"filename"`
+	StorageKey string `json:"-"`
+	ThumbKey   string `json:"-"` // empty if not an image
+	MIME       string `json:"mime"`
+	Size       int64  `json:"size"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	UploaderID uint64 `json:"uploader_id"`
+
+	// filled by service from storage
+	URL      string `json:"url"`
+	ThumbURL string `json:"thumb_url,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
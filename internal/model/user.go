@@ -17,6 +17,7 @@ type User struct {
 
 	Nickname string `json:"nickname"`
 	Avatar   string `json:"avatar"`
+	Locale   string `json:"locale"` // preferred language, empty follows Accept-Language
 
 	Role      int       `json:"role"`
 	Status    int       `json:"status"`
@@ -0,0 +1,33 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+)
+
+//go:embed docs.html
+var docsHTML []byte
+
+// Handler serves the document as JSON, it is built once.
+func Handler(uploadPrefix string) http.Handler {
+	doc, err := json.Marshal(Build(uploadPrefix))
+	if err != nil {
+		panic(err) // the document only holds maps, slices and strings
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(doc)
+	})
+}
+
+// DocsHandler serves a self-contained page browsing /api/openapi.json,
+// no CDN needed.
+func DocsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(docsHTML)
+	})
+}
@@ -0,0 +1,177 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schemas collects the JSON schemas of Go types, named structs are put
+// in components and referenced by $ref.
+type schemas struct {
+	named map[string]any
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// of returns the schema of the type of v, nil v means JSON null.
+func (s *schemas) of(v any) any {
+	if v == nil {
+		return map[string]any{"type": "null"}
+	}
+	return s.typ(reflect.TypeOf(v))
+}
+
+func (s *schemas) typ(t reflect.Type) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		inner := s.typ(t.Elem())
+		if ty, ok := inner["type"].(string); ok {
+			inner["type"] = []string{ty, "null"}
+			return inner
+		}
+		return map[string]any{"oneOf": []any{inner, map[string]any{"type": "null"}}}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.typ(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.typ(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := schemaName(t)
+		if _, ok := s.named[name]; !ok {
+			s.named[name] = nil // placeholder, breaks recursion
+			s.named[name] = s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+// schemaName names a struct in components, generic instances are named
+// after their type arguments: Page[pkg.Article] is ArticlePage.
+func schemaName(t reflect.Type) string {
+	name, args, generic := strings.Cut(t.Name(), "[")
+	if !generic {
+		return name
+	}
+	var prefix string
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		arg = strings.TrimLeft(arg, "*[]")
+		prefix += arg[strings.LastIndex(arg, ".")+1:]
+	}
+	return prefix + name
+}
+
+// object builds the schema of a struct from its json tags, embedded
+// structs are flattened like encoding/json does.
+func (s *schemas) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	s.fields(t, props, &required)
+	obj := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
+
+func (s *schemas) fields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			s.fields(f.Type, props, required)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := s.typ(f.Type)
+		if constrain(prop, f.Type, f.Tag.Get("validate")) {
+			*required = append(*required, name)
+		}
+		props[name] = prop
+	}
+}
+
+// constrain adds the rules of a pkg/validate tag to prop and reports
+// whether the field is required.
+func constrain(prop map[string]any, t reflect.Type, tag string) (required bool) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "pattern=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+		name, param, _ := strings.Cut(part, "=")
+		n, _ := strconv.Atoi(param)
+		switch name {
+		case "required":
+			required = true
+		case "min", "max":
+			// minLength, maxItems, minimum...
+			switch t.Kind() {
+			case reflect.String:
+				prop[name+"Length"] = n
+			case reflect.Slice, reflect.Array:
+				prop[name+"Items"] = n
+			default:
+				prop[name+"imum"] = n
+			}
+		case "maxbytes":
+			// json schema counts characters only
+			prop["description"] = "at most " + param + " bytes in UTF-8"
+		case "pattern":
+			prop["pattern"] = param
+		case "enum":
+			prop["enum"] = strings.Split(param, "|")
+		}
+	}
+	return required
+}
+
+// queryParams describes the fields of a struct bound by query params,
+// named by their json tags.
+func (s *schemas) queryParams(t reflect.Type) []any {
+	var params []any
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		schema := s.typ(f.Type)
+		required := constrain(schema, f.Type, f.Tag.Get("validate"))
+		params = append(params, map[string]any{
+			"name":     name,
+			"in":       "query",
+			"required": required,
+			"schema":   schema,
+		})
+	}
+	return params
+}
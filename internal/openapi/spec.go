@@ -0,0 +1,376 @@
+// Package openapi describes the HTTP API as an OpenAPI 3.1 document.
+// The operations below must list every route of router.LoadRouters,
+// a test in package router fails when they drift apart.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gngtwhh/WBlog/internal/handler"
+	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+)
+
+type access int
+
+const (
+	public access = iota
+	user          // Auth
+	admin         // Auth + AdminOnly
+)
+
+type param struct {
+	Name     string
+	In       string // query or path
+	Type     string
+	Desc     string
+	Required bool
+}
+
+func pathParam(name, typ, desc string) param {
+	return param{Name: name, In: "path", Type: typ, Desc: desc, Required: true}
+}
+
+func query(name, typ, desc string, required bool) param {
+	return param{Name: name, In: "query", Type: typ, Desc: desc, Required: required}
+}
+
+var idQuery = query("id", "integer", "id of the resource", true)
+
+// raw is a reply that is not wrapped in the {code,msg,data} envelope.
+type raw struct {
+	ContentType string
+	Body        any // Go value describing the body, nil for no schema
+}
+
+type operation struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	Access  access
+	Params  []param
+	Query   any    // struct bound by query params, its fields follow Params
+	Body    any    // JSON request body
+	Upload  string // multipart/form-data file field
+	Data    any    // data of the envelope, nil is null
+	Status  int    // success status, 0 is 200
+	Raw     *raw
+}
+
+func operations(uploadPrefix string) []operation {
+	html := &raw{ContentType: "text/html"}
+	pageQuery := query("page", "integer", "page of published articles, 10 per page", false)
+	return []operation{
+		// pages
+		{Method: "GET", Path: "/", Tag: "pages", Summary: "Index page, server rendered",
+			Params: []param{pageQuery}, Raw: html},
+		{Method: "GET", Path: "/index", Tag: "pages", Summary: "Index page, server rendered",
+			Params: []param{pageQuery}, Raw: html},
+		{Method: "GET", Path: "/admin", Tag: "pages", Summary: "Admin page", Raw: html},
+		{Method: "GET", Path: "/article/{id}", Tag: "pages", Summary: "Article page, server rendered; drafts and unknown ids are 404",
+			Params: []param{pathParam("id", "integer", "article id")}, Raw: html},
+		{Method: "GET", Path: "/static/{path}", Tag: "pages", Summary: "Static asset, fingerprinted names are immutable",
+			Params: []param{pathParam("path", "string", "asset path")}, Raw: &raw{ContentType: "application/octet-stream"}},
+		{Method: "GET", Path: uploadPrefix + "{path}", Tag: "pages", Summary: "Uploaded file",
+			Params: []param{pathParam("path", "string", "storage key")}, Raw: &raw{ContentType: "application/octet-stream"}},
+
+		{Method: "GET", Path: "/feed.xml", Tag: "pages", Summary: "RSS 2.0 feed of the newest published articles",
+			Raw: &raw{ContentType: "application/rss+xml"}},
+		{Method: "GET", Path: "/atom.xml", Tag: "pages", Summary: "Atom feed of the newest published articles",
+			Raw: &raw{ContentType: "application/atom+xml"}},
+		{Method: "GET", Path: "/feed.json", Tag: "pages", Summary: "JSON Feed 1.1 of the newest published articles",
+			Raw: &raw{ContentType: "application/feed+json"}},
+		{Method: "GET", Path: "/sitemap.xml", Tag: "pages", Summary: "Sitemap of published articles, or an index of sitemaps if they do not fit in one",
+			Raw: &raw{ContentType: "application/xml"}},
+		{Method: "GET", Path: "/sitemap/{file}", Tag: "pages", Summary: "Sitemap listed by the index",
+			Params: []param{pathParam("file", "string", "pages.xml or articles-{n}.xml")}, Raw: &raw{ContentType: "application/xml"}},
+		{Method: "GET", Path: "/robots.txt", Tag: "pages", Summary: "Crawler rules", Raw: &raw{ContentType: "text/plain"}},
+
+		// articles, v1
+		{Method: "GET", Path: "/api/list-articles", Tag: "articles", Summary: "List articles, newest first",
+			Params: []param{query("page", "integer", "page index, from 1, not needed with cursor", false), query("pagesize", "integer", "articles per page, at most 100", true),
+				query("cursor", "string", "next_cursor of the previous page, empty for the first; switches to keyset pagination, sort must be created_at", false)},
+			Query: handler.ListArticlesQuery{}, Data: handler.Page[model.Article]{}},
+		{Method: "GET", Path: "/api/articles-count", Tag: "articles", Summary: "Count published articles, lists carry total too", Data: CountData{}},
+		{Method: "GET", Path: "/api/get-article", Tag: "articles", Summary: "Get a published article",
+			Params: []param{idQuery}, Data: model.Article{}},
+		{Method: "POST", Path: "/api/create-article", Tag: "articles", Summary: "Create an article", Access: admin,
+			Body: handler.CreateArticleRequest{}, Data: IDData{}},
+		{Method: "POST", Path: "/api/update-article", Tag: "articles", Summary: "Replace an article", Access: admin,
+			Body: handler.UpdateArticleRequest{}, Data: IDData{}},
+		{Method: "DELETE", Path: "/api/delete-article", Tag: "articles", Summary: "Delete an article", Access: admin,
+			Params: []param{idQuery}},
+		{Method: "GET", Path: "/api/admin/list-articles", Tag: "articles", Summary: "List articles including drafts, status defaults to all", Access: admin,
+			Params: []param{query("page", "integer", "page index, from 1, not needed with cursor", false), query("pagesize", "integer", "articles per page, at most 100", true),
+				query("cursor", "string", "next_cursor of the previous page, empty for the first; switches to keyset pagination, sort must be created_at", false)},
+			Query: handler.ListArticlesQuery{}, Data: handler.Page[model.Article]{}},
+		{Method: "GET", Path: "/api/admin/get-article", Tag: "articles", Summary: "Get an article, drafts included", Access: admin,
+			Params: []param{idQuery}, Data: model.Article{}},
+
+		// v2
+		{Method: "GET", Path: "/api/v2/articles", Tag: "v2", Summary: "List articles, newest first",
+			Params: []param{query("page", "integer", "page index, default 1", false), query("page_size", "integer", "default 10, at most 100", false),
+				query("cursor", "string", "next_cursor of the previous page, empty for the first; switches to keyset pagination, sort must be created_at", false)},
+			Query: handler.ListArticlesQuery{}, Data: handler.Page[model.Article]{}},
+		{Method: "POST", Path: "/api/v2/articles", Tag: "v2", Summary: "Create an article", Access: admin,
+			Body: handler.CreateArticleRequest{}, Data: model.Article{}, Status: http.StatusCreated},
+		{Method: "GET", Path: "/api/v2/articles/{id}", Tag: "v2", Summary: "Get a published article",
+			Params: []param{pathParam("id", "integer", "article id")}, Data: model.Article{}},
+		{Method: "PATCH", Path: "/api/v2/articles/{id}", Tag: "v2", Summary: "Update the given fields of an article", Access: admin,
+			Params: []param{pathParam("id", "integer", "article id")}, Body: handler.PatchArticleRequest{}, Data: model.Article{}},
+		{Method: "DELETE", Path: "/api/v2/articles/{id}", Tag: "v2", Summary: "Delete an article", Access: admin,
+			Params: []param{pathParam("id", "integer", "article id")}},
+		{Method: "GET", Path: "/api/v2/admin/articles", Tag: "v2", Summary: "List articles including drafts, status defaults to all", Access: admin,
+			Params: []param{query("page", "integer", "page index, default 1", false), query("page_size", "integer", "default 10, at most 100", false),
+				query("cursor", "string", "next_cursor of the previous page, empty for the first; switches to keyset pagination, sort must be created_at", false)},
+			Query: handler.ListArticlesQuery{}, Data: handler.Page[model.Article]{}},
+		{Method: "GET", Path: "/api/v2/admin/articles/{id}", Tag: "v2", Summary: "Get an article, drafts included", Access: admin,
+			Params: []param{pathParam("id", "integer", "article id")}, Data: model.Article{}},
+		{Method: "GET", Path: "/api/v2/articles/{id}/comments", Tag: "v2", Summary: "List comments of an article, newest first",
+			Params: []param{pathParam("id", "integer", "article id"), query("page", "integer", "page index, default 1", false), query("page_size", "integer", "default 10, at most 100", false),
+				query("cursor", "string", "next_cursor of the previous page, empty for the first; switches to keyset pagination", false)},
+			Data: handler.Page[*model.Comment]{}},
+		{Method: "POST", Path: "/api/v2/articles/{id}/comments", Tag: "v2", Summary: "Comment on an article", Access: user,
+			Params: []param{pathParam("id", "integer", "article id")}, Body: handler.CreateCommentV2Request{}, Data: IDData{}, Status: http.StatusCreated},
+		{Method: "GET", Path: "/api/v2/users/{id}", Tag: "v2", Summary: "Public profile of a user",
+			Params: []param{pathParam("id", "integer", "user id")}, Data: PublicUser{}},
+
+		// probes and build info
+		{Method: "GET", Path: "/healthz", Tag: "system", Summary: "Liveness probe",
+			Raw: &raw{ContentType: "application/json", Body: HealthStatus{}}},
+		{Method: "GET", Path: "/readyz", Tag: "system", Summary: "Readiness probe, 503 if a critical dependency is down",
+			Raw: &raw{ContentType: "application/json", Body: ReadyStatus{}}},
+		{Method: "GET", Path: "/version", Tag: "system", Summary: "Build info",
+			Raw: &raw{ContentType: "application/json", Body: VersionInfo{}}},
+		{Method: "GET", Path: "/metrics", Tag: "system", Summary: "Prometheus metrics, bearer token required if configured",
+			Raw: &raw{ContentType: "text/plain"}},
+		{Method: "GET", Path: "/.well-known/jwks.json", Tag: "system", Summary: "Public keys verifying tokens",
+			Raw: &raw{ContentType: "application/jwk-set+json", Body: JWKSet{}}},
+		{Method: "GET", Path: "/api/openapi.json", Tag: "system", Summary: "This document",
+			Raw: &raw{ContentType: "application/json"}},
+		{Method: "GET", Path: "/api/docs", Tag: "system", Summary: "Interactive API docs", Raw: html},
+
+		// users
+		{Method: "GET", Path: "/api/userinfo", Tag: "users", Summary: "Public profile of a user",
+			Params: []param{idQuery}, Data: PublicUser{}},
+		{Method: "POST", Path: "/api/user/register", Tag: "users", Summary: "Register",
+			Body: handler.RegisterRequest{}, Data: IDData{}},
+		{Method: "POST", Path: "/api/user/login", Tag: "users", Summary: "Log in, the token is set as cookie if use_cookie is true",
+			Body: handler.LoginRequest{}, Data: LoginData{}},
+		{Method: "GET", Path: "/api/user/profile", Tag: "users", Summary: "Profile of the current user", Access: user,
+			Data: model.User{}},
+		{Method: "POST", Path: "/api/user/update", Tag: "users", Summary: "Update the profile, empty fields are left unchanged; a new locale reissues the token", Access: user,
+			Body: handler.UpdateProfileRequest{}, Data: TokenData{}},
+		{Method: "POST", Path: "/api/user/update-password", Tag: "users", Summary: "Change the password", Access: user,
+			Body: handler.ChangePasswordRequest{}},
+		{Method: "POST", Path: "/api/user/upload-avatar", Tag: "users", Summary: "Upload an avatar image", Access: user,
+			Upload: "avatar", Data: AvatarData{}},
+		{Method: "POST", Path: "/api/user/logout", Tag: "users", Summary: "Revoke the current token", Access: user},
+
+		// media
+		{Method: "POST", Path: "/api/upload-media", Tag: "media", Summary: "Upload a file", Access: admin,
+			Upload: "file", Data: model.Media{}},
+		{Method: "GET", Path: "/api/list-media", Tag: "media", Summary: "List uploaded files", Access: admin,
+			Params: []param{query("page", "integer", "page index, default 1", false), query("pagesize", "integer", "default 20, at most 100", false)},
+			Data:   MediaList{}},
+		{Method: "DELETE", Path: "/api/delete-media", Tag: "media", Summary: "Delete an uploaded file", Access: admin,
+			Params: []param{idQuery}},
+
+		// oauth
+		{Method: "GET", Path: "/api/oauth/providers", Tag: "oauth", Summary: "Configured login providers", Data: ProviderList{}},
+		{Method: "GET", Path: "/api/oauth/{provider}/login", Tag: "oauth", Summary: "Redirect to the provider",
+			Params: []param{pathParam("provider", "string", "provider name")}, Status: http.StatusFound, Raw: &raw{}},
+		{Method: "GET", Path: "/api/oauth/{provider}/callback", Tag: "oauth", Summary: "Finish login at the provider",
+			Params: []param{pathParam("provider", "string", "provider name"), query("code", "string", "authorization code", true),
+				query("state", "string", "state set by login", true), query("error", "string", "error reported by the provider", false)},
+			Data: LoginData{}},
+
+		// comments
+		{Method: "GET", Path: "/api/list-comments", Tag: "comments", Summary: "List comments of an article, newest first",
+			Params: []param{query("article_id", "integer", "article id", true), query("page", "integer", "page index, default 1", false), query("page_size", "integer", "default 10, at most 100", false),
+				query("cursor", "string", "next_cursor of the previous page, empty for the first; switches to keyset pagination", false)},
+			Data: handler.Page[*model.Comment]{}},
+		{Method: "POST", Path: "/api/create-comment", Tag: "comments", Summary: "Comment on an article", Access: user,
+			Body: handler.CreateCommentReq{}, Data: IDData{}},
+	}
+}
+
+// Routes returns "METHOD /path" of every documented operation.
+func Routes(uploadPrefix string) []string {
+	ops := operations(uploadPrefix)
+	routes := make([]string, 0, len(ops))
+	for _, op := range ops {
+		routes = append(routes, op.Method+" "+op.Path)
+	}
+	return routes
+}
+
+// Build returns the OpenAPI document.
+func Build(uploadPrefix string) map[string]any {
+	s := &schemas{named: map[string]any{}}
+	paths := map[string]any{}
+	for _, op := range operations(uploadPrefix) {
+		item, _ := paths[op.Path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = op.build(s)
+	}
+
+	s.named["Envelope"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"code": map[string]any{"type": "integer", "description": "0 on success, see ErrCode"},
+			"msg":  map[string]any{"type": "string", "description": "localized by Accept-Language or the user's locale"},
+			"data": map[string]any{},
+		},
+		"required": []string{"code", "msg", "data"},
+	}
+	s.named["ErrorEnvelope"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"code": map[string]any{"$ref": "#/components/schemas/ErrCode"},
+			"msg":  map[string]any{"type": "string"},
+			"data": map[string]any{"oneOf": []any{map[string]any{"type": "null"}, s.of(ValidationErrors{})}},
+		},
+		"required": []string{"code", "msg", "data"},
+	}
+	s.named["Problem"] = map[string]any{
+		"type":        "object",
+		"description": "RFC 7807 problem details, replied instead of ErrorEnvelope when http.problem_json is on",
+		"properties": map[string]any{
+			"type":   map[string]any{"type": "string"},
+			"title":  map[string]any{"type": "string"},
+			"status": map[string]any{"type": "integer"},
+			"detail": map[string]any{"type": "string"},
+			"code":   map[string]any{"$ref": "#/components/schemas/ErrCode"},
+			"data":   s.of(ValidationErrors{}),
+		},
+	}
+	s.named["ErrCode"] = errCodeSchema()
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "WBlog API",
+			"version": "2",
+			"description": "Replies are wrapped in {code,msg,data}. The HTTP status follows code, " +
+				"see ErrCode. Write requests authenticated by cookie must echo the csrf_token cookie in X-CSRF-Token.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": s.named,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"cookieAuth": map[string]any{"type": "apiKey", "in": "cookie", "name": "token"},
+			},
+		},
+	}
+}
+
+func (op operation) build(s *schemas) map[string]any {
+	o := map[string]any{
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+		"operationId": operationID(op.Method, op.Path),
+	}
+	if op.Access != public {
+		o["security"] = []any{map[string]any{"bearerAuth": []string{}}, map[string]any{"cookieAuth": []string{}}}
+	}
+	if op.Access == admin {
+		o["description"] = "Admin only."
+	}
+
+	if len(op.Params) > 0 || op.Query != nil {
+		params := make([]any, 0, len(op.Params))
+		for _, p := range op.Params {
+			params = append(params, map[string]any{
+				"name":        p.Name,
+				"in":          p.In,
+				"required":    p.Required,
+				"description": p.Desc,
+				"schema":      map[string]any{"type": p.Type},
+			})
+		}
+		if op.Query != nil {
+			params = append(params, s.queryParams(reflect.TypeOf(op.Query))...)
+		}
+		o["parameters"] = params
+	}
+
+	switch {
+	case op.Body != nil:
+		o["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": s.of(op.Body)}},
+		}
+	case op.Upload != "":
+		o["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{"multipart/form-data": map[string]any{"schema": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{op.Upload: map[string]any{"type": "string", "contentMediaType": "application/octet-stream"}},
+				"required":   []string{op.Upload},
+			}}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ok := map[string]any{"description": http.StatusText(status)}
+	if op.Raw != nil {
+		if op.Raw.ContentType != "" {
+			media := map[string]any{}
+			if op.Raw.Body != nil {
+				media["schema"] = s.of(op.Raw.Body)
+			}
+			ok["content"] = map[string]any{op.Raw.ContentType: media}
+		}
+		o["responses"] = map[string]any{fmt.Sprint(status): ok}
+		return o
+	}
+	ok["content"] = map[string]any{"application/json": map[string]any{"schema": map[string]any{
+		"allOf": []any{
+			map[string]any{"$ref": "#/components/schemas/Envelope"},
+			map[string]any{"properties": map[string]any{"data": s.of(op.Data)}},
+		},
+	}}}
+	o["responses"] = map[string]any{
+		fmt.Sprint(status): ok,
+		"default": map[string]any{
+			"description": "Failure, the status follows code",
+			"content": map[string]any{
+				"application/json":         map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/ErrorEnvelope"}},
+				"application/problem+json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Problem"}},
+			},
+		},
+	}
+	return o
+}
+
+// operationID turns "GET /api/v2/articles/{id}" into get_api_v2_articles_id.
+func operationID(method, path string) string {
+	id := strings.ToLower(method) + strings.NewReplacer("/", "_", "-", "_", ".", "_", "{", "", "}", "").Replace(path)
+	return strings.TrimSuffix(id, "_")
+}
+
+// errCodeSchema lists every errcode with its HTTP status and message.
+func errCodeSchema() map[string]any {
+	codes := errcode.Codes()
+	lines := make([]string, 0, len(codes))
+	for _, c := range codes {
+		lines = append(lines, fmt.Sprintf("- `%d` (HTTP %d): %s", c, errcode.HTTPStatus(c), errcode.GetMsg(c)))
+	}
+	return map[string]any{
+		"type":        "integer",
+		"enum":        codes,
+		"description": strings.Join(lines, "\n"),
+	}
+}
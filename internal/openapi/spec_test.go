@@ -0,0 +1,21 @@
+package openapi
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func TestBuildRefsResolve(t *testing.T) {
+	doc := Build("/uploads/")
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	for _, m := range regexp.MustCompile(`"#/components/schemas/(\w+)"`).FindAllSubmatch(data, -1) {
+		if _, ok := schemas[string(m[1])]; !ok {
+			t.Errorf("unresolved $ref %s", m[1])
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package openapi
+
+import (
+	"time"
+
+	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/pkg/utils"
+	"github.com/gngtwhh/WBlog/pkg/validate"
+)
+
+// Shapes of replies the handlers build from maps, named here so the
+// spec can describe them.
+
+type IDData struct {
+	ID uint64 `json:"id"`
+}
+
+type CountData struct {
+	Count int64 `json:"count"`
+}
+
+type LoginUser struct {
+	ID       uint64 `json:"id"`
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar"`
+	Role     int    `json:"role"`
+}
+
+type LoginData struct {
+	Token string    `json:"token,omitempty"` // absent when the token is set as cookie
+	User  LoginUser `json:"user"`
+}
+
+type PublicUser struct {
+	ID        uint64    `json:"id"`
+	Nickname  string    `json:"nickname"`
+	Avatar    string    `json:"avatar"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AvatarData struct {
+	Avatar string `json:"avatar"`
+}
+
+// TokenData is the reply of a profile update changing the locale.
+type TokenData struct {
+	Token string `json:"token,omitempty"` // absent when the token is set as cookie
+}
+
+type MediaList struct {
+	List  []model.Media `json:"list"`
+	Total int64         `json:"total"`
+}
+
+type ProviderList struct {
+	Providers []string `json:"providers"`
+}
+
+type HealthStatus struct {
+	Status string `json:"status"`
+}
+
+type CheckResult struct {
+	Status    string  `json:"status"` // up, down, degraded
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type ReadyStatus struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type VersionInfo struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	Module    string `json:"module"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  string `json:"modified,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []utils.JWK `json:"keys"`
+}
+
+// ValidationErrors is the data of a ParamError replied by a failed
+// validate tag.
+type ValidationErrors struct {
+	Errors []validate.FieldError `json:"errors"`
+}
@@ -1,11 +1,16 @@
 package render
 
 import (
+	"bytes"
 	"html/template"
 	"net/http"
 
 	"github.com/gngtwhh/WBlog/pkg/errcode"
 	"github.com/gngtwhh/WBlog/pkg/response"
+	"github.com/gngtwhh/WBlog/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Renderer struct {
@@ -22,23 +27,49 @@ func Init(t map[string]*template.Template, entry string) {
 	}
 }
 
+// Loaded reports whether templates have been compiled.
+func Loaded() bool {
+	return renderer != nil && len(renderer.tmpls) > 0
+}
+
 // Execute executes a template with the given data and writes the result to the response writer.
-func Execute(w http.ResponseWriter, name string, data interface{}) {
+func Execute(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
+	ExecuteStatus(w, r, http.StatusOK, name, data)
+}
+
+// ExecuteStatus is Execute with another status code, e.g. for error pages.
+func ExecuteStatus(w http.ResponseWriter, r *http.Request, status int, name string, data interface{}) {
+	_, span := tracing.Start(r.Context(), "render.Execute", trace.WithAttributes(attribute.String("template", name)))
+	defer span.End()
+
 	if renderer == nil {
-		response.Fail(w, errcode.ServerError, "template not initialized")
+		span.SetStatus(codes.Error, "template not initialized")
+		response.Fail(w, errcode.ServerError)
 		// http.Error(w, "template not initialized", http.StatusInternalServerError)
+		return
 	}
 	tmpl, ok := renderer.tmpls[name]
 	if !ok {
-		response.Fail(w, errcode.ServerError, "template not found")
+		span.SetStatus(codes.Error, "template not found: "+name)
+		response.Fail(w, errcode.ServerError)
 		// http.Error(w, "template not found", http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// pages are not translated, only API messages are
+	w.Header().Set("Content-Language", "zh-CN")
+	// render into a buffer first, a failed template must not leave a
+	// half written page behind the status code
+	var buf bytes.Buffer
 	// err := tmpl.Execute(w, data)
-	err := tmpl.ExecuteTemplate(w, renderer.entry, data)
+	err := tmpl.ExecuteTemplate(&buf, renderer.entry, data)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		response.Fail(w, errcode.ServerError)
 		// http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	w.WriteHeader(status)
+	buf.WriteTo(w)
 }
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ArticleSort is a column article lists can be ordered by.
+type ArticleSort string
+
+const (
+	SortCreated  ArticleSort = "created_at"
+	SortUpdated  ArticleSort = "updated_at"
+	SortViews    ArticleSort = "view_count"
+	SortComments ArticleSort = "comment_count"
+)
+
+// ErrCursorSort is returned when a cursor is used with another order
+// than created_at, keyset positions only exist in that one.
+var ErrCursorSort = errors.New("cursor requires sort by created_at")
+
+// ArticleQuery selects a page of articles. Zero fields do not filter, the
+// zero value lists all articles newest first.
+type ArticleQuery struct {
+	Sort   ArticleSort // empty is SortCreated
+	Asc    bool
+	Author string
+	Status string    // model.ArticlePublished or model.ArticleDraft
+	From   time.Time // created at or after
+	To     time.Time // created before
+	// Compact leaves Abstract empty, for lists that only show titles
+	Compact bool
+	// WithContent fills Content too, lists leave it empty otherwise
+	WithContent bool
+
+	Limit  int
+	Offset int
+	Cursor *Cursor // keyset position, replaces Offset
+}
+
+// Filter returns the part of q that decides which articles match,
+// without ordering, paging and projection.
+func (q ArticleQuery) Filter() ArticleQuery {
+	return ArticleQuery{Author: q.Author, Status: q.Status, From: q.From, To: q.To}
+}
+
+// Key identifies q, for cache keys.
+func (q ArticleQuery) Key() string {
+	pos := ""
+	if q.Cursor != nil {
+		pos = q.Cursor.String()
+	}
+	return fmt.Sprintf("%s:%t:%s:%s:%d:%d:%t:%t:%d:%d:%s", q.Sort, q.Asc, url.QueryEscape(q.Author), q.Status,
+		unix(q.From), unix(q.To), q.Compact, q.WithContent, q.Limit, q.Offset, pos)
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// where returns the WHERE clause of q's filters and cursor, with args.
+func (q ArticleQuery) where() (string, []any) {
+	var conds []string
+	var args []any
+	if q.Author != "" {
+		conds = append(conds, "author = ?")
+		args = append(args, q.Author)
+	}
+	if q.Status != "" {
+		conds = append(conds, "status = ?")
+		args = append(args, q.Status)
+	}
+	if !q.From.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, q.From.UTC().Format(sqliteTime))
+	}
+	if !q.To.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, q.To.UTC().Format(sqliteTime))
+	}
+	if cond, condArgs := q.Cursor.after(q.Asc); cond != "" {
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+// orderBy returns the ORDER BY clause, id breaks ties so pages are stable.
+func (q ArticleQuery) orderBy() (string, error) {
+	col := q.Sort
+	switch col {
+	case "":
+		col = SortCreated
+	case SortCreated, SortUpdated, SortViews, SortComments:
+	default:
+		return "", fmt.Errorf("unknown article sort %q", col)
+	}
+	if q.Cursor != nil && col != SortCreated {
+		return "", ErrCursorSort
+	}
+	dir := "DESC"
+	if q.Asc {
+		dir = "ASC"
+	}
+	return fmt.Sprintf("ORDER BY %s %s, id %s", col, dir, dir), nil
+}
@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log/slog"
 
 	"github.com/gngtwhh/WBlog/internal/model"
@@ -22,14 +24,13 @@ func NewArticleRepo(db *sql.DB, log *slog.Logger) *ArticleRepo {
 
 // Create inserts a new article into the database.
 // article.ID will be set if Create success.
-func (r *ArticleRepo) Create(article *model.Article) error {
+func (r *ArticleRepo) Create(ctx context.Context, article *model.Article) error {
 	query := `
-		INSERT INTO articles (title,author,content,abstract,view_count)
-		VALUES (?,?,?,?,?)
+		INSERT INTO articles (title,author,content,abstract,status,view_count)
+		VALUES (?,?,?,?,?,?)
 	`
-	// TODO: add context to control timeout
-	result, err := r.db.Exec(query, article.Title, article.Author, article.Content, article.Abstract,
-		article.ViewCount)
+	result, err := r.db.ExecContext(ctx, query, article.Title, article.Author, article.Content, article.Abstract,
+		article.Status, article.ViewCount)
 	if err != nil {
 		return err
 	}
@@ -41,16 +42,16 @@ func (r *ArticleRepo) Create(article *model.Article) error {
 	return nil
 }
 
-func (r *ArticleRepo) GetByID(id int64) (model.Article, error) {
+func (r *ArticleRepo) GetByID(ctx context.Context, id int64) (model.Article, error) {
 	query := `
-			SELECT id, title, author, content, abstract, view_count, created_at, updated_at
+			SELECT content, ` + fmt.Sprintf(articleColumns, "abstract") + `
 			FROM articles
 			WHERE id = ?
 		`
 	var a model.Article
-	err := r.db.QueryRow(query, id).Scan(
-		&a.ID, &a.Title, &a.Author, &a.Content, &a.Abstract,
-		&a.ViewCount, &a.CreatedAt, &a.UpdatedAt,
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&a.Content, &a.ID, &a.Title, &a.Author, &a.Abstract, &a.Status,
+		&a.ViewCount, &a.CommentCount, &a.CreatedAt, &a.UpdatedAt,
 	)
 
 	if err != nil {
@@ -59,17 +60,19 @@ func (r *ArticleRepo) GetByID(id int64) (model.Article, error) {
 	return a, nil
 }
 
-func (r *ArticleRepo) Update(article *model.Article) error {
+// Update writes article, an empty Status leaves the stored status.
+func (r *ArticleRepo) Update(ctx context.Context, article *model.Article) error {
 	query := `
 		UPDATE articles
-		SET title=?, author=?, content=?, abstract=?
+		SET title=?, author=?, content=?, abstract=?, status=COALESCE(NULLIF(?, ''), status)
 		WHERE id=?
 	`
-	res, err := r.db.Exec(query,
+	res, err := r.db.ExecContext(ctx, query,
 		article.Title,
 		article.Author,
 		article.Content,
 		article.Abstract,
+		article.Status,
 		article.ID,
 	)
 	if err != nil {
@@ -86,9 +89,9 @@ func (r *ArticleRepo) Update(article *model.Article) error {
 	return nil
 }
 
-func (r *ArticleRepo) Delete(id int64) error {
+func (r *ArticleRepo) Delete(ctx context.Context, id int64) error {
 	query := "DELETE FROM articles WHERE id = ?"
-	res, err := r.db.Exec(query, id)
+	res, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -103,32 +106,52 @@ func (r *ArticleRepo) Delete(id int64) error {
 	return nil
 }
 
-// GetList retrieves a list of articles from the database.
-func (r *ArticleRepo) GetList(limit, offset int) ([]model.Article, error) {
-	if limit <= 0 {
-		limit = 10
+// articleColumns are selected by article reads, comment_count is computed
+// so it can not drift from the comments table.
+const articleColumns = `id, title, author, %s, status, view_count,
+	(SELECT count(*) FROM comments WHERE comments.article_id = articles.id) AS comment_count,
+	created_at, updated_at`
+
+// GetList retrieves the articles selected by q. Content is left empty
+// unless q.WithContent, and Abstract too if q.Compact.
+func (r *ArticleRepo) GetList(ctx context.Context, q ArticleQuery) ([]model.Article, error) {
+	if q.Limit <= 0 {
+		q.Limit = 10
+	}
+	if q.Offset < 0 || q.Cursor != nil {
+		q.Offset = 0
+	}
+	orderBy, err := q.orderBy()
+	if err != nil {
+		return nil, err
 	}
-	if offset < 0 {
-		offset = 0
+	abstract, content := "abstract", "'' AS content"
+	if q.Compact {
+		abstract = "'' AS abstract"
 	}
+	if q.WithContent {
+		content = "content"
+	}
+	where, args := q.where()
 
 	query := `
-		SELECT id, title, author, abstract, view_count, created_at, updated_at
+		SELECT ` + content + `, ` + fmt.Sprintf(articleColumns, abstract) + `
 		FROM articles
-		ORDER BY created_at DESC
+		` + where + `
+		` + orderBy + `
 		LIMIT ? OFFSET ?
 	`
-	rows, err := r.db.Query(query, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, append(args, q.Limit, q.Offset)...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	articles := make([]model.Article, 0, limit)
+	articles := make([]model.Article, 0, q.Limit)
 	for rows.Next() {
 		var article model.Article
-		if err := rows.Scan(&article.ID, &article.Title, &article.Author, &article.Abstract,
-			&article.ViewCount, &article.CreatedAt, &article.UpdatedAt); err != nil {
+		if err := rows.Scan(&article.Content, &article.ID, &article.Title, &article.Author, &article.Abstract, &article.Status,
+			&article.ViewCount, &article.CommentCount, &article.CreatedAt, &article.UpdatedAt); err != nil {
 			return nil, err
 		}
 		articles = append(articles, article)
@@ -139,12 +162,49 @@ func (r *ArticleRepo) GetList(limit, offset int) ([]model.Article, error) {
 
 	return articles, nil
 }
-func (r *ArticleRepo) Count() (int64, error) {
+
+// Count counts the articles matching the filters of q.
+func (r *ArticleRepo) Count(ctx context.Context, q ArticleQuery) (int64, error) {
 	var count int64
-	query := "SELECT count(*) FROM articles"
-	err := r.db.QueryRow(query).Scan(&count)
+	where, args := q.Filter().where()
+	query := "SELECT count(*) FROM articles " + where
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
 	return count, nil
 }
+
+// ListStamps lists the id and updated_at of published articles with id
+// above after, by id. It reads nothing else, so sitemaps of many
+// thousand articles stay cheap, and pages by id instead of OFFSET.
+func (r *ArticleRepo) ListStamps(ctx context.Context, after int64, limit int) ([]model.ArticleStamp, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, updated_at FROM articles
+		WHERE status = ? AND id > ?
+		ORDER BY id
+		LIMIT ?
+	`, model.ArticlePublished, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stamps := make([]model.ArticleStamp, 0, min(limit, 1024))
+	for rows.Next() {
+		var s model.ArticleStamp
+		if err := rows.Scan(&s.ID, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		stamps = append(stamps, s)
+	}
+	return stamps, rows.Err()
+}
+
+// LastPublishedID returns the highest id of a published article, 0 if
+// there is none.
+func (r *ArticleRepo) LastPublishedID(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM articles WHERE status = ?", model.ArticlePublished).Scan(&id)
+	return id, err
+}
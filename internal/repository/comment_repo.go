@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"log/slog"
 
@@ -20,12 +21,12 @@ func NewCommentRepo(db *sql.DB, log *slog.Logger) *CommentRepo {
 	}
 }
 
-func (r *CommentRepo) Create(comment *model.Comment) error {
+func (r *CommentRepo) Create(ctx context.Context, comment *model.Comment) error {
 	query := `
 		INSERT INTO comments (user_id, article_id, content, username)
 		VALUES (?, ?, ?, ?)
 	`
-	res, err := r.db.Exec(query, comment.UserID, comment.ArticleID, comment.Content, comment.Username)
+	res, err := r.db.ExecContext(ctx, query, comment.UserID, comment.ArticleID, comment.Content, comment.Username)
 	if err != nil {
 		r.log.Error("Create comment failed", slog.String("err", err.Error()))
 		return err
@@ -36,16 +37,42 @@ func (r *CommentRepo) Create(comment *model.Comment) error {
 	return nil
 }
 
-func (r *CommentRepo) ListByArticleID(articleID int64, limit, offset int) ([]*model.Comment, error) {
+func (r *CommentRepo) ListByArticleID(ctx context.Context, articleID int64, limit, offset int) ([]*model.Comment, error) {
 	query := `
 		SELECT id, user_id, article_id, content, username, created_at
 		FROM comments
 		WHERE article_id = ?
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
 		LIMIT ? OFFSET ?
 	`
+	return r.list(ctx, query, articleID, limit, offset)
+}
+
+// ListByArticleIDAfter lists comments after cursor by keyset.
+func (r *CommentRepo) ListByArticleIDAfter(ctx context.Context, articleID int64, cursor *Cursor, limit int) ([]*model.Comment, error) {
+	where, args := cursor.after(false)
+	if where != "" {
+		where = "AND " + where
+	}
+	query := `
+		SELECT id, user_id, article_id, content, username, created_at
+		FROM comments
+		WHERE article_id = ? ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`
+	args = append([]any{articleID}, args...)
+	return r.list(ctx, query, append(args, limit)...)
+}
+
+func (r *CommentRepo) CountByArticleID(ctx context.Context, articleID int64) (int64, error) {
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT count(*) FROM comments WHERE article_id = ?", articleID).Scan(&count)
+	return count, err
+}
 
-	rows, err := r.db.Query(query, articleID, limit, offset)
+func (r *CommentRepo) list(ctx context.Context, query string, args ...any) ([]*model.Comment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqliteTime is the layout of CURRENT_TIMESTAMP, cursors compare against
+// the stored text so they must use it too.
+const sqliteTime = "2006-01-02 15:04:05"
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the keyset position after a row in (created_at, id) order,
+// descending unless asked otherwise. A nil *Cursor starts from the first
+// row.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint64
+}
+
+// CursorAfter returns the cursor following a row.
+func CursorAfter(createdAt time.Time, id uint64) *Cursor {
+	return &Cursor{CreatedAt: createdAt, ID: id}
+}
+
+// String encodes c opaquely, clients must not rely on its format.
+func (c *Cursor) String() string {
+	raw := c.CreatedAt.UTC().Format(sqliteTime) + "|" + strconv.FormatUint(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a cursor made by String, an empty string is nil.
+func ParseCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	ts, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(sqliteTime, ts)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// after returns the keyset condition and its args, empty for a nil cursor.
+func (c *Cursor) after(asc bool) (string, []any) {
+	if c == nil {
+		return "", nil
+	}
+	op := "<"
+	if asc {
+		op = ">"
+	}
+	return "(created_at, id) " + op + " (?, ?)", []any{c.CreatedAt.UTC().Format(sqliteTime), c.ID}
+}
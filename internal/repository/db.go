@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -11,6 +12,23 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// migrations upgrade a database created by the baseline schema (version
+// 1), migrations[i] brings it to version i+2. Only ever append.
+var migrations = []string{
+	// 2: preferred language of messages
+	`ALTER TABLE users ADD COLUMN locale TEXT DEFAULT '';`,
+	// 3: keyset pagination over (created_at, id)
+	`CREATE INDEX IF NOT EXISTS idx_articles_created_id ON articles(created_at DESC, id DESC);
+	CREATE INDEX IF NOT EXISTS idx_comments_article_created_id ON comments(article_id, created_at DESC, id DESC);`,
+	// 4: drafts, and filters of article lists
+	`ALTER TABLE articles ADD COLUMN status TEXT NOT NULL DEFAULT 'published';
+	CREATE INDEX IF NOT EXISTS idx_articles_status_created_id ON articles(status, created_at DESC, id DESC);
+	CREATE INDEX IF NOT EXISTS idx_articles_author ON articles(author);`,
+}
+
+// SchemaVersion is the PRAGMA user_version of a fully migrated database.
+var SchemaVersion = 1 + len(migrations)
+
 func InitDB(dsn string) (*sql.DB, error) {
 	// create parent dir if not exists
 	dbPath := dsn
@@ -104,9 +122,48 @@ func createTable(db *sql.DB) error {
 	END;
 
 	-- -----------------------------------------------------
-	-- 4. Indices
+	-- 4. User identities (OAuth2/OIDC accounts)
+	-- -----------------------------------------------------
+	CREATE TABLE IF NOT EXISTS user_identities (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id    INTEGER NOT NULL,
+		provider   TEXT NOT NULL,
+		subject    TEXT NOT NULL,
+		email      TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(provider, subject)
+	);
+
+	CREATE TRIGGER IF NOT EXISTS trg_user_identities_updated_at
+	AFTER UPDATE ON user_identities
+	BEGIN
+		UPDATE user_identities SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+	END;
+
+	-- -----------------------------------------------------
+	-- 5. Media
+	-- -----------------------------------------------------
+	CREATE TABLE IF NOT EXISTS media (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		hash        TEXT NOT NULL UNIQUE, -- sha256, for dedupe
+		filename    TEXT NOT NULL,
+		storage_key TEXT NOT NULL,
+		thumb_key   TEXT DEFAULT '',
+		mime        TEXT NOT NULL,
+		size        INTEGER NOT NULL,
+		width       INTEGER DEFAULT 0,
+		height      INTEGER DEFAULT 0,
+		uploader_id INTEGER NOT NULL,
+		created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- -----------------------------------------------------
+	-- 6. Indices
 	-- -----------------------------------------------------
 	CREATE INDEX IF NOT EXISTS idx_comments_article_id ON comments(article_id);
+	CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+	CREATE INDEX IF NOT EXISTS idx_media_created_at ON media(created_at DESC);
 	CREATE INDEX IF NOT EXISTS idx_articles_created_at ON articles(created_at DESC);
 	`
 
@@ -114,6 +171,52 @@ func createTable(db *sql.DB) error {
 		log.Printf("Init database schema failed: %v", err)
 		return err
 	}
+	return migrate(db)
+}
+
+// migrate applies the pending migrations, each in its own transaction
+// together with the version bump.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version == 0 {
+		// fresh database, or one created before versioning: baseline only
+		version = 1
+		if _, err := db.Exec("PRAGMA user_version = 1"); err != nil {
+			return err
+		}
+	}
+	for i := version - 1; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+2, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+2)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("Database migrated to schema version %d", i+2)
+	}
+	return nil
+}
 
+// CheckSchema reports whether the database schema is at SchemaVersion.
+func CheckSchema(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version < SchemaVersion {
+		return fmt.Errorf("schema version %d, want %d", version, SchemaVersion)
+	}
 	return nil
 }
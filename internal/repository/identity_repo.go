@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/gngtwhh/WBlog/internal/model"
+)
+
+// IdentityRepo implements the repository.IdentityRepository interface.
+type IdentityRepo struct {
+	db  *sql.DB
+	log *slog.Logger
+}
+
+func NewIdentityRepo(db *sql.DB, log *slog.Logger) *IdentityRepo {
+	return &IdentityRepo{
+		db:  db,
+		log: log.With("component", "identity_repo"),
+	}
+}
+
+// CreateWithUser inserts user and identity linked to it in one
+// transaction, so a failed link leaves no user behind. Both IDs are set
+// on success.
+func (r *IdentityRepo) CreateWithUser(ctx context.Context, user *model.User, identity *model.UserIdentity) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertUser(ctx, tx, user); err != nil {
+		return err
+	}
+	identity.UserID = user.ID
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES (?, ?, ?, ?)
+	`, identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	identity.ID = uint64(id)
+	return nil
+}
+
+func (r *IdentityRepo) GetByProviderSubject(provider, subject string) (*model.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, updated_at
+		FROM user_identities
+		WHERE provider = ? AND subject = ?
+	`
+	i := &model.UserIdentity{}
+	err := r.db.QueryRow(query, provider, subject).Scan(
+		&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt, &i.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (r *IdentityRepo) ListByUserID(userID uint64) ([]*model.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, updated_at
+		FROM user_identities
+		WHERE user_id = ?
+		ORDER BY id
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*model.UserIdentity, 0)
+	for rows.Next() {
+		i := &model.UserIdentity{}
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email,
+			&i.CreatedAt, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
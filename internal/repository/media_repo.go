@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"github.com/gngtwhh/WBlog/internal/model"
+)
+
+// MediaRepo implements the repository.MediaRepository interface.
+type MediaRepo struct {
+	db  *sql.DB
+	log *slog.Logger
+}
+
+func NewMediaRepo(db *sql.DB, log *slog.Logger) *MediaRepo {
+	return &MediaRepo{
+		db:  db,
+		log: log.With("component", "media_repo"),
+	}
+}
+
+const mediaColumns = `id, hash, filename, storage_key, thumb_key, mime, size, width, height, uploader_id, created_at`
+
+func scanMedia(row interface{ Scan(...any) error }) (*model.Media, error) {
+	m := &model.Media{}
+	err := row.Scan(&m.ID, &m.Hash, &m.Filename, &m.StorageKey, &m.ThumbKey, &m.MIME,
+		&m.Size, &m.Width, &m.Height, &m.UploaderID, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (r *MediaRepo) Create(media *model.Media) error {
+	query := `
+		INSERT INTO media (hash, filename, storage_key, thumb_key, mime, size, width, height, uploader_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	res, err := r.db.Exec(query, media.Hash, media.Filename, media.StorageKey, media.ThumbKey,
+		media.MIME, media.Size, media.Width, media.Height, media.UploaderID)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	media.ID = uint64(id)
+	return nil
+}
+
+func (r *MediaRepo) GetByID(id uint64) (*model.Media, error) {
+	query := "SELECT " + mediaColumns + " FROM media WHERE id = ?"
+	return scanMedia(r.db.QueryRow(query, id))
+}
+
+func (r *MediaRepo) GetByHash(hash string) (*model.Media, error) {
+	query := "SELECT " + mediaColumns + " FROM media WHERE hash = ?"
+	return scanMedia(r.db.QueryRow(query, hash))
+}
+
+func (r *MediaRepo) Delete(id uint64) error {
+	res, err := r.db.Exec("DELETE FROM media WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetList returns media ordered from newest to oldest.
+func (r *MediaRepo) GetList(limit, offset int) ([]*model.Media, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	query := "SELECT " + mediaColumns + " FROM media ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*model.Media, 0, limit)
+	for rows.Next() {
+		m, err := scanMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *MediaRepo) Count() (int64, error) {
+	var count int64
+	if err := r.db.QueryRow("SELECT count(*) FROM media").Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -1,29 +1,58 @@
 package repository
 
-import "github.com/gngtwhh/WBlog/internal/model"
+import (
+	"context"
+
+	"github.com/gngtwhh/WBlog/internal/model"
+)
 
 // ArticleRepository defines the methods for interacting with articles in the repository.
 type ArticleRepository interface {
 	// Single article
-	Create(article *model.Article) error
-	GetByID(id int64) (model.Article, error)
-	Update(article *model.Article) error
-	Delete(id int64) error
+	Create(ctx context.Context, article *model.Article) error
+	GetByID(ctx context.Context, id int64) (model.Article, error)
+	Update(ctx context.Context, article *model.Article) error
+	Delete(ctx context.Context, id int64) error
 	// list
-	GetList(limit, offset int) ([]model.Article, error)
-	Count() (int64, error)
+	GetList(ctx context.Context, q ArticleQuery) ([]model.Article, error)
+	// Count only uses the filters of q
+	Count(ctx context.Context, q ArticleQuery) (int64, error)
+	// published articles with id above after, by id, for sitemaps
+	ListStamps(ctx context.Context, after int64, limit int) ([]model.ArticleStamp, error)
+	LastPublishedID(ctx context.Context) (int64, error)
 }
 
 // UserRepository defines the method for managing users of blog webpages.
 type UserRepository interface {
-	Create(user *model.User) error
-	GetByUsername(username string) (*model.User, error)
-	GetByID(id uint64) (*model.User, error)
-	Update(user *model.User) error
+	Create(ctx context.Context, user *model.User) error
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByID(ctx context.Context, id uint64) (*model.User, error)
+	Update(ctx context.Context, user *model.User) error
 }
 
 // CommentRepository defines the method for managing comments of articles.
 type CommentRepository interface {
-	Create(comment *model.Comment) error
-	ListByArticleID(articleID int64, limit, offset int) ([]*model.Comment, error)
+	Create(ctx context.Context, comment *model.Comment) error
+	ListByArticleID(ctx context.Context, articleID int64, limit, offset int) ([]*model.Comment, error)
+	// ListByArticleIDAfter lists limit comments after cursor, newest first
+	ListByArticleIDAfter(ctx context.Context, articleID int64, cursor *Cursor, limit int) ([]*model.Comment, error)
+	CountByArticleID(ctx context.Context, articleID int64) (int64, error)
+}
+
+// IdentityRepository defines the method for managing external identities linked to users.
+type IdentityRepository interface {
+	// CreateWithUser creates user and its identity atomically
+	CreateWithUser(ctx context.Context, user *model.User, identity *model.UserIdentity) error
+	GetByProviderSubject(provider, subject string) (*model.UserIdentity, error)
+	ListByUserID(userID uint64) ([]*model.UserIdentity, error)
+}
+
+// MediaRepository defines the method for managing uploaded media.
+type MediaRepository interface {
+	Create(media *model.Media) error
+	GetByID(id uint64) (*model.Media, error)
+	GetByHash(hash string) (*model.Media, error)
+	Delete(id uint64) error
+	GetList(limit, offset int) ([]*model.Media, error)
+	Count() (int64, error)
 }
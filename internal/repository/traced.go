@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts a client span of a repository call.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("db.system", "sqlite"))
+	return tracing.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+type tracedArticleRepo struct{ next ArticleRepository }
+
+// TraceArticleRepo wraps repo so every call is recorded as a span.
+func TraceArticleRepo(repo ArticleRepository) ArticleRepository {
+	return &tracedArticleRepo{next: repo}
+}
+
+func (r *tracedArticleRepo) Create(ctx context.Context, article *model.Article) error {
+	ctx, span := startSpan(ctx, "ArticleRepo.Create")
+	err := r.next.Create(ctx, article)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *tracedArticleRepo) GetByID(ctx context.Context, id int64) (model.Article, error) {
+	ctx, span := startSpan(ctx, "ArticleRepo.GetByID", attribute.Int64("article.id", id))
+	a, err := r.next.GetByID(ctx, id)
+	tracing.End(span, err, sql.ErrNoRows)
+	return a, err
+}
+
+func (r *tracedArticleRepo) Update(ctx context.Context, article *model.Article) error {
+	ctx, span := startSpan(ctx, "ArticleRepo.Update", attribute.Int64("article.id", int64(article.ID)))
+	err := r.next.Update(ctx, article)
+	tracing.End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (r *tracedArticleRepo) Delete(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, "ArticleRepo.Delete", attribute.Int64("article.id", id))
+	err := r.next.Delete(ctx, id)
+	tracing.End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (r *tracedArticleRepo) GetList(ctx context.Context, q ArticleQuery) ([]model.Article, error) {
+	ctx, span := startSpan(ctx, "ArticleRepo.GetList", attribute.String("sort", string(q.Sort)),
+		attribute.Int("limit", q.Limit), attribute.Int("offset", q.Offset))
+	list, err := r.next.GetList(ctx, q)
+	tracing.End(span, err)
+	return list, err
+}
+
+func (r *tracedArticleRepo) Count(ctx context.Context, q ArticleQuery) (int64, error) {
+	ctx, span := startSpan(ctx, "ArticleRepo.Count")
+	n, err := r.next.Count(ctx, q)
+	tracing.End(span, err)
+	return n, err
+}
+
+func (r *tracedArticleRepo) ListStamps(ctx context.Context, after int64, limit int) ([]model.ArticleStamp, error) {
+	ctx, span := startSpan(ctx, "ArticleRepo.ListStamps", attribute.Int64("after", after), attribute.Int("limit", limit))
+	list, err := r.next.ListStamps(ctx, after, limit)
+	tracing.End(span, err)
+	return list, err
+}
+
+func (r *tracedArticleRepo) LastPublishedID(ctx context.Context) (int64, error) {
+	ctx, span := startSpan(ctx, "ArticleRepo.LastPublishedID")
+	id, err := r.next.LastPublishedID(ctx)
+	tracing.End(span, err)
+	return id, err
+}
+
+type tracedUserRepo struct{ next UserRepository }
+
+// TraceUserRepo wraps repo so every call is recorded as a span.
+func TraceUserRepo(repo UserRepository) UserRepository {
+	return &tracedUserRepo{next: repo}
+}
+
+func (r *tracedUserRepo) Create(ctx context.Context, user *model.User) error {
+	ctx, span := startSpan(ctx, "UserRepo.Create")
+	err := r.next.Create(ctx, user)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *tracedUserRepo) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	ctx, span := startSpan(ctx, "UserRepo.GetByUsername")
+	u, err := r.next.GetByUsername(ctx, username)
+	tracing.End(span, err, sql.ErrNoRows)
+	return u, err
+}
+
+func (r *tracedUserRepo) GetByID(ctx context.Context, id uint64) (*model.User, error) {
+	ctx, span := startSpan(ctx, "UserRepo.GetByID", attribute.Int64("user.id", int64(id)))
+	u, err := r.next.GetByID(ctx, id)
+	tracing.End(span, err, sql.ErrNoRows)
+	return u, err
+}
+
+func (r *tracedUserRepo) Update(ctx context.Context, user *model.User) error {
+	ctx, span := startSpan(ctx, "UserRepo.Update", attribute.Int64("user.id", int64(user.ID)))
+	err := r.next.Update(ctx, user)
+	tracing.End(span, err, sql.ErrNoRows)
+	return err
+}
+
+type tracedCommentRepo struct{ next CommentRepository }
+
+// TraceCommentRepo wraps repo so every call is recorded as a span.
+func TraceCommentRepo(repo CommentRepository) CommentRepository {
+	return &tracedCommentRepo{next: repo}
+}
+
+func (r *tracedCommentRepo) Create(ctx context.Context, comment *model.Comment) error {
+	ctx, span := startSpan(ctx, "CommentRepo.Create", attribute.Int64("article.id", int64(comment.ArticleID)))
+	err := r.next.Create(ctx, comment)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *tracedCommentRepo) ListByArticleID(ctx context.Context, articleID int64, limit, offset int) ([]*model.Comment, error) {
+	ctx, span := startSpan(ctx, "CommentRepo.ListByArticleID", attribute.Int64("article.id", articleID))
+	list, err := r.next.ListByArticleID(ctx, articleID, limit, offset)
+	tracing.End(span, err)
+	return list, err
+}
+
+func (r *tracedCommentRepo) ListByArticleIDAfter(ctx context.Context, articleID int64, cursor *Cursor, limit int) ([]*model.Comment, error) {
+	ctx, span := startSpan(ctx, "CommentRepo.ListByArticleIDAfter", attribute.Int64("article.id", articleID))
+	list, err := r.next.ListByArticleIDAfter(ctx, articleID, cursor, limit)
+	tracing.End(span, err)
+	return list, err
+}
+
+func (r *tracedCommentRepo) CountByArticleID(ctx context.Context, articleID int64) (int64, error) {
+	ctx, span := startSpan(ctx, "CommentRepo.CountByArticleID", attribute.Int64("article.id", articleID))
+	n, err := r.next.CountByArticleID(ctx, articleID)
+	tracing.End(span, err)
+	return n, err
+}
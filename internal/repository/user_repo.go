@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"log/slog"
 
@@ -20,18 +21,29 @@ func NewUserRepo(db *sql.DB, log *slog.Logger) *UserRepo {
 	}
 }
 
-func (r *UserRepo) Create(user *model.User) error {
+func (r *UserRepo) Create(ctx context.Context, user *model.User) error {
+	return insertUser(ctx, r.db, user)
+}
+
+// execer is a *sql.DB or *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insertUser inserts user through db, user.ID is set on success.
+func insertUser(ctx context.Context, db execer, user *model.User) error {
 	query := `
-		INSERT INTO users (username, password,nickname,avatar,role,status)
-		VALUES (?,?,?,?,?,?)
+		INSERT INTO users (username, password,nickname,avatar,role,status,locale)
+		VALUES (?,?,?,?,?,?,?)
 	`
-	result, err := r.db.Exec(query,
+	result, err := db.ExecContext(ctx, query,
 		user.Username,
 		user.Password,
 		user.Nickname,
 		user.Avatar,
 		user.Role,
 		user.Status,
+		user.Locale,
 	)
 	if err != nil {
 		return err
@@ -44,13 +56,13 @@ func (r *UserRepo) Create(user *model.User) error {
 	return nil
 }
 
-func (r *UserRepo) GetByUsername(username string) (*model.User, error) {
+func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	query := `
-		SELECT id, username, password, nickname, avatar, role, status, created_at, updated_at
+		SELECT id, username, password, nickname, avatar, role, status, locale, created_at, updated_at
 		FROM users
 		WHERE username = ?
 	`
-	row := r.db.QueryRow(query, username)
+	row := r.db.QueryRowContext(ctx, query, username)
 	user := &model.User{}
 	err := row.Scan(
 		&user.ID,
@@ -60,6 +72,7 @@ func (r *UserRepo) GetByUsername(username string) (*model.User, error) {
 		&user.Avatar,
 		&user.Role,
 		&user.Status,
+		&user.Locale,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -69,13 +82,13 @@ func (r *UserRepo) GetByUsername(username string) (*model.User, error) {
 	return user, nil
 }
 
-func (r *UserRepo) GetByID(id uint64) (*model.User, error) {
+func (r *UserRepo) GetByID(ctx context.Context, id uint64) (*model.User, error) {
 	query := `
-		SELECT id, username, password, nickname, avatar, role, status, created_at, updated_at
+		SELECT id, username, password, nickname, avatar, role, status, locale, created_at, updated_at
 		FROM users
 		WHERE id = ?
 	`
-	row := r.db.QueryRow(query, id)
+	row := r.db.QueryRowContext(ctx, query, id)
 	user := &model.User{}
 	err := row.Scan(
 		&user.ID,
@@ -85,6 +98,7 @@ func (r *UserRepo) GetByID(id uint64) (*model.User, error) {
 		&user.Avatar,
 		&user.Role,
 		&user.Status,
+		&user.Locale,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -94,19 +108,20 @@ func (r *UserRepo) GetByID(id uint64) (*model.User, error) {
 	return user, nil
 }
 
-func (r *UserRepo) Update(user *model.User) error {
+func (r *UserRepo) Update(ctx context.Context, user *model.User) error {
 	query := `
 			UPDATE users
-			SET password=?, nickname=?, avatar=?, role=?, status=?, updated_at=CURRENT_TIMESTAMP
+			SET password=?, nickname=?, avatar=?, role=?, status=?, locale=?, updated_at=CURRENT_TIMESTAMP
 			WHERE id=?
 		`
 
-	res, err := r.db.Exec(query,
+	res, err := r.db.ExecContext(ctx, query,
 		user.Password,
 		user.Nickname,
 		user.Avatar,
 		user.Role,
 		user.Status,
+		user.Locale,
 		user.ID,
 	)
 	if err != nil {
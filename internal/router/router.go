@@ -3,17 +3,86 @@ package router
 import (
 	"log/slog"
 	"net/http"
+	"os"
 
+	"github.com/gngtwhh/WBlog/internal/assets"
+	"github.com/gngtwhh/WBlog/internal/cache"
 	"github.com/gngtwhh/WBlog/internal/config"
 	"github.com/gngtwhh/WBlog/internal/handler"
+	"github.com/gngtwhh/WBlog/internal/metrics"
 	"github.com/gngtwhh/WBlog/internal/middleware"
+	"github.com/gngtwhh/WBlog/internal/openapi"
 )
 
-func LoadRouters(app *handler.App, logger *slog.Logger) http.Handler {
-	router := http.NewServeMux()
+// mux records the patterns it registers, so they can be checked against
+// the OpenAPI document.
+type mux struct {
+	*http.ServeMux
+	patterns []string
+}
+
+// filesOnly hides the directories of a file system, so that a file server
+// answers 404 for them instead of listing what is inside.
+type filesOnly struct {
+	http.FileSystem
+}
+
+func (fs filesOnly) Open(name string) (http.File, error) {
+	f, err := fs.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if st, err := f.Stat(); err != nil || st.IsDir() {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+	return f, nil
+}
+
+func (m *mux) Handle(pattern string, h http.Handler) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.Handle(pattern, h)
+}
+
+func (m *mux) HandleFunc(pattern string, h func(http.ResponseWriter, *http.Request)) {
+	m.Handle(pattern, http.HandlerFunc(h))
+}
+
+func LoadRouters(app *handler.App, store cache.Store, manifest *assets.Manifest, logger *slog.Logger) http.Handler {
+	var handler http.Handler = routes(app, store, manifest)
+	handler = middleware.Metrics(handler)
+	handler = middleware.CSRF(handler)
+	handler = middleware.LimitBody(config.Cfg.GetMaxBodyBytes())(handler)
+	if !config.Cfg.HTTP.DisableCompression {
+		handler = middleware.Compress(config.Cfg.GetCompressMinSize())(handler)
+	}
+	handler = middleware.Recovery(metrics.ReportPanic)(handler)
+	handler = middleware.Language(handler)
+	// probes are only logged when they fail
+	handler = middleware.RequestLogger(logger, "/healthz", "/readyz", "/metrics")(handler)
+	handler = middleware.Tracing(handler)
+
+	return handler
+}
+
+// routes registers every route, each must be described in package openapi.
+func routes(app *handler.App, store cache.Store, manifest *assets.Manifest) *mux {
+	router := &mux{ServeMux: http.NewServeMux()}
+	policy := middleware.FailOpen
+	if config.Cfg.Cache.BlacklistFailClosed {
+		policy = middleware.FailClosed
+	}
+	auth := middleware.Auth(store, policy)
+	// ETag / Last-Modified revalidation with per-route Cache-Control
+	cacheable := func(path string, next http.HandlerFunc) http.HandlerFunc {
+		return middleware.Conditional(config.Cfg.GetCacheControl(path))(next)
+	}
 
 	// static resources
-	router.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.Dir(config.Cfg.App.StaticDir))))
+	router.Handle("GET /static/", http.StripPrefix("/static/", manifest.Handler()))
+	// uploaded files
+	uploadPrefix := config.Cfg.GetUploadURLPrefix()
+	router.Handle("GET "+uploadPrefix, http.StripPrefix(uploadPrefix, http.FileServer(filesOnly{http.Dir(config.Cfg.GetUploadDir())})))
 
 	// root and /index
 	// TODO: use app
@@ -26,14 +95,57 @@ func LoadRouters(app *handler.App, logger *slog.Logger) http.Handler {
 	// article page
 	router.HandleFunc("GET /article/{id}", app.Index.ArticlePage)
 
+	// feeds of published articles
+	router.HandleFunc("GET /feed.xml", cacheable("/feed.xml", app.Feed.RSS))
+	router.HandleFunc("GET /atom.xml", cacheable("/atom.xml", app.Feed.Atom))
+	router.HandleFunc("GET /feed.json", cacheable("/feed.json", app.Feed.JSON))
+
+	// crawlers
+	router.HandleFunc("GET /sitemap.xml", cacheable("/sitemap.xml", app.Sitemap.Sitemap))
+	router.HandleFunc("GET /sitemap/{file}", cacheable("/sitemap/{file}", app.Sitemap.Part))
+	router.HandleFunc("GET /robots.txt", cacheable("/robots.txt", app.Sitemap.Robots))
+
 	// article api
-	router.HandleFunc("GET /api/list-articles", app.Article.ListArticles)
-	router.HandleFunc("GET /api/articles-count", app.Article.Count)
-	router.HandleFunc("GET /api/get-article", app.Article.GetArticle)
+	router.HandleFunc("GET /api/list-articles", cacheable("/api/list-articles", app.Article.ListArticles))
+	router.HandleFunc("GET /api/articles-count", cacheable("/api/articles-count", app.Article.Count))
+	router.HandleFunc("GET /api/get-article", cacheable("/api/get-article", app.Article.GetArticle))
+
+	router.HandleFunc("POST /api/create-article", auth(middleware.AdminOnly(app.Article.Create)))
+	router.HandleFunc("POST /api/update-article", auth(middleware.AdminOnly(app.Article.Update)))
+	router.HandleFunc("DELETE /api/delete-article", auth(middleware.AdminOnly(app.Article.Delete)))
+	// drafts are only listed and read by admins
+	router.HandleFunc("GET /api/admin/list-articles", auth(middleware.AdminOnly(app.Article.ListArticles)))
+	router.HandleFunc("GET /api/admin/get-article", auth(middleware.AdminOnly(app.Article.GetArticle)))
+
+	// v2 api, resource oriented; v1 above is kept for the templates
+	{
+		router.HandleFunc("GET /api/v2/articles", cacheable("/api/v2/articles", app.Article.ListV2))
+		router.HandleFunc("POST /api/v2/articles", auth(middleware.AdminOnly(app.Article.CreateV2)))
+		router.HandleFunc("GET /api/v2/articles/{id}", cacheable("/api/v2/articles/{id}", app.Article.GetV2))
+		router.HandleFunc("PATCH /api/v2/articles/{id}", auth(middleware.AdminOnly(app.Article.PatchV2)))
+		router.HandleFunc("DELETE /api/v2/articles/{id}", auth(middleware.AdminOnly(app.Article.DeleteV2)))
+		// drafts are only served here, the public reads above are cacheable
+		router.HandleFunc("GET /api/v2/admin/articles", auth(middleware.AdminOnly(app.Article.ListV2)))
+		router.HandleFunc("GET /api/v2/admin/articles/{id}", auth(middleware.AdminOnly(app.Article.GetV2)))
+
+		router.HandleFunc("GET /api/v2/articles/{id}/comments", cacheable("/api/v2/articles/{id}/comments", app.Comment.ListV2))
+		router.HandleFunc("POST /api/v2/articles/{id}/comments", auth(app.Comment.CreateV2))
+
+		router.HandleFunc("GET /api/v2/users/{id}", app.User.GetV2)
+	}
 
-	router.HandleFunc("POST /api/create-article", app.Article.Create)
-	router.HandleFunc("POST /api/update-article", app.Article.Update)
-	router.HandleFunc("DELETE /api/delete-article", app.Article.Delete)
+	// probes and build info
+	router.HandleFunc("GET /healthz", app.Health.Healthz)
+	router.HandleFunc("GET /readyz", app.Health.Readyz)
+	router.HandleFunc("GET /version", app.Health.Version)
+	router.Handle("GET /metrics", metrics.Handler(config.Cfg.Metrics.Token))
+
+	// public keys for verifying tokens
+	router.HandleFunc("GET /.well-known/jwks.json", app.WellKnown.JWKS)
+
+	// api description
+	router.Handle("GET /api/openapi.json", openapi.Handler(uploadPrefix))
+	router.Handle("GET /api/docs", openapi.DocsHandler())
 
 	// user api
 	router.HandleFunc("GET /api/userinfo", app.User.GetUserInfo)
@@ -41,22 +153,31 @@ func LoadRouters(app *handler.App, logger *slog.Logger) http.Handler {
 	router.HandleFunc("POST /api/user/login", app.User.Login)
 	// authentication required
 	{
-		router.HandleFunc("GET /api/user/profile", middleware.Auth(app.User.GetProfile))
-		router.HandleFunc("POST /api/user/update", middleware.Auth(app.User.UpdateProfile))
-		router.HandleFunc("POST /api/user/update-password", middleware.Auth(app.User.UpdatePassword))
-		router.HandleFunc("POST /api/user/upload-avatar", middleware.Auth(app.User.UploadAvatar))
-		router.HandleFunc("POST /api/user/logout", middleware.Auth(app.User.Logout))
+		router.HandleFunc("GET /api/user/profile", auth(app.User.GetProfile))
+		router.HandleFunc("POST /api/user/update", auth(app.User.UpdateProfile))
+		router.HandleFunc("POST /api/user/update-password", auth(app.User.UpdatePassword))
+		router.HandleFunc("POST /api/user/upload-avatar", auth(app.User.UploadAvatar))
+		router.HandleFunc("POST /api/user/logout", auth(app.User.Logout))
+	}
+
+	// media api, admin only
+	{
+		router.HandleFunc("POST /api/upload-media", auth(middleware.AdminOnly(app.Media.Upload)))
+		router.HandleFunc("GET /api/list-media", auth(middleware.AdminOnly(app.Media.List)))
+		router.HandleFunc("DELETE /api/delete-media", auth(middleware.AdminOnly(app.Media.Delete)))
 	}
 
+	// oauth2 / oidc login
+	router.HandleFunc("GET /api/oauth/providers", app.OAuth.Providers)
+	router.HandleFunc("GET /api/oauth/{provider}/login", app.OAuth.Login)
+	router.HandleFunc("GET /api/oauth/{provider}/callback", app.OAuth.Callback)
+
 	// comment api
-	router.HandleFunc("GET /api/list-comments", app.Comment.ListComments)
+	router.HandleFunc("GET /api/list-comments", cacheable("/api/list-comments", app.Comment.ListComments))
 	// authentication required
 	{
-		router.HandleFunc("POST /api/create-comment", middleware.Auth(app.Comment.CreateComment))
+		router.HandleFunc("POST /api/create-comment", auth(app.Comment.CreateComment))
 	}
 
-	var handler http.Handler = router
-	handler = middleware.RequestLogger(logger)(handler)
-
-	return handler
+	return router
 }
@@ -0,0 +1,106 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gngtwhh/WBlog/internal/assets"
+	"github.com/gngtwhh/WBlog/internal/config"
+	"github.com/gngtwhh/WBlog/internal/handler"
+	"github.com/gngtwhh/WBlog/internal/openapi"
+)
+
+// TestRoutesDocumented fails when a route is added without describing it
+// in package openapi, or the spec keeps a route that is gone.
+func TestRoutesDocumented(t *testing.T) {
+	config.Cfg = &config.Config{}
+	m := routes(&handler.App{}, nil, &assets.Manifest{})
+
+	registered := make([]string, 0, len(m.patterns))
+	for _, p := range m.patterns {
+		// subtree patterns serve files below them
+		if strings.HasSuffix(p, "/") && !strings.HasSuffix(p, " /") {
+			p += "{path}"
+		}
+		registered = append(registered, p)
+	}
+	documented := openapi.Routes(config.Cfg.GetUploadURLPrefix())
+
+	for _, r := range registered {
+		if !slices.Contains(documented, r) {
+			t.Errorf("route %q is not in the OpenAPI document", r)
+		}
+	}
+	for _, d := range documented {
+		if !slices.Contains(registered, d) {
+			t.Errorf("OpenAPI document has %q, which is not routed", d)
+		}
+	}
+}
+
+// TestCompressedRequestLog checks the request log of a compressed reply
+// through the whole middleware chain, Compress must find the log writer
+// below the wrappers of the middleware in between.
+func TestCompressedRequestLog(t *testing.T) {
+	config.Cfg = &config.Config{}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	h := LoadRouters(&handler.App{}, nil, &assets.Manifest{}, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if enc := rec.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", enc)
+	}
+
+	var entry struct {
+		Encoding string `json:"ENCODING"`
+		RawSize  int64  `json:"RAW_SIZE"`
+		Size     int64  `json:"SIZE"`
+	}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if strings.Contains(line, `"msg":"HTTP"`) {
+			json.Unmarshal([]byte(line), &entry)
+		}
+	}
+	if entry.Encoding != "gzip" || entry.RawSize <= entry.Size {
+		t.Errorf("log ENCODING = %q, RAW_SIZE = %d, SIZE = %d; want gzip and a raw size above the wire size",
+			entry.Encoding, entry.RawSize, entry.Size)
+	}
+}
+
+// TestUploadsNoListing checks that uploaded files are served but the
+// upload directories are not listed.
+func TestUploadsNoListing(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "media"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "media", "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config.Cfg = &config.Config{Upload: config.UploadConfig{Dir: dir}}
+	m := routes(&handler.App{}, nil, &assets.Manifest{})
+
+	for path, want := range map[string]int{
+		"/uploads/media/a.txt": http.StatusOK,
+		"/uploads/media/":      http.StatusNotFound,
+		"/uploads/":            http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
@@ -7,95 +7,176 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
+	"strconv"
 	"time"
 
 	"github.com/gngtwhh/WBlog/internal/cache"
+	"github.com/gngtwhh/WBlog/internal/metrics"
 	"github.com/gngtwhh/WBlog/internal/model"
 	"github.com/gngtwhh/WBlog/internal/repository"
-	"github.com/redis/go-redis/v9"
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"golang.org/x/sync/singleflight"
 )
 
 var (
-	ErrArticleNotFound = errors.New("article not found")
+	ErrArticleNotFound = errcode.New(errcode.ArticleNotFound, "article not found")
+	ErrArticleEmpty    = errcode.New(errcode.ArticleEmpty, "empty article title or content")
+)
+
+const (
+	articleDetailTTL   = time.Hour
+	articleListTTL     = 10 * time.Minute
+	articleNotFoundTTL = time.Minute
 )
 
 type ArticleService struct {
-	repo repository.ArticleRepository
-	log  *slog.Logger
+	repo  repository.ArticleRepository
+	cache cache.Store
+	// coalesces concurrent cache misses of the same key into one db query
+	group singleflight.Group
+	log   *slog.Logger
 }
 
-func NewArticleService(repo repository.ArticleRepository, logger *slog.Logger) *ArticleService {
+func NewArticleService(repo repository.ArticleRepository, store cache.Store, logger *slog.Logger) *ArticleService {
 	return &ArticleService{
-		repo: repo,
-		log:  logger.With("componend", "article_service"),
+		repo:  repo,
+		cache: store,
+		log:   logger.With("componend", "article_service"),
 	}
 }
 
-func (svc *ArticleService) ListArticles(limit, offset int) ([]model.Article, error) {
-	articles, err := svc.repo.GetList(limit, offset)
+// ListArticles lists the articles selected by q.
+func (svc *ArticleService) ListArticles(ctx context.Context, q repository.ArticleQuery) ([]model.Article, error) {
+	cacheKey := fmt.Sprintf("%sv%d:%s", cache.PrefixArticleList, svc.listVersion(ctx), q.Key())
+	var articles []model.Article
+	if svc.getCached(ctx, "article_list", cacheKey, &articles) {
+		return articles, nil
+	}
+
+	v, err, _ := svc.group.Do(cacheKey, func() (any, error) {
+		// shared by coalesced callers, so it must outlive the first one
+		ctx := context.WithoutCancel(ctx)
+		articles, err := svc.repo.GetList(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		svc.setCached(ctx, cacheKey, articles, articleListTTL)
+		return articles, nil
+	})
 	if err != nil {
 		svc.log.Error("failed to list articles", "err", err)
 		return nil, err
 	}
-	return articles, nil
+	return v.([]model.Article), nil
 }
 
-func (svc *ArticleService) Count() (int64, error) {
-	count, err := svc.repo.Count()
+// Count counts the articles matching the filters of q.
+func (svc *ArticleService) Count(ctx context.Context, q repository.ArticleQuery) (int64, error) {
+	cacheKey := fmt.Sprintf("%sv%d:%s", cache.PrefixArticleCount, svc.listVersion(ctx), q.Filter().Key())
+	var count int64
+	if svc.getCached(ctx, "article_count", cacheKey, &count) {
+		return count, nil
+	}
+
+	v, err, _ := svc.group.Do(cacheKey, func() (any, error) {
+		ctx := context.WithoutCancel(ctx)
+		count, err := svc.repo.Count(ctx, q.Filter())
+		if err != nil {
+			return nil, err
+		}
+		svc.setCached(ctx, cacheKey, count, articleListTTL)
+		return count, nil
+	})
 	if err != nil {
 		svc.log.Error("failed to count articles", "err", err)
 		return 0, err
 	}
-	return count, nil
+	return v.(int64), nil
+}
+
+// Stamps lists the id and updated_at of published articles with id above
+// after, by id. It skips the list cache, sitemaps would fill it with
+// entries of thousands of articles each.
+func (svc *ArticleService) Stamps(ctx context.Context, after int64, limit int) ([]model.ArticleStamp, error) {
+	stamps, err := svc.repo.ListStamps(ctx, after, limit)
+	if err != nil {
+		svc.log.Error("failed to list article stamps", "err", err)
+		return nil, err
+	}
+	return stamps, nil
+}
+
+// LastPublishedID returns the highest id of a published article, 0 if
+// there is none.
+func (svc *ArticleService) LastPublishedID(ctx context.Context) (int64, error) {
+	id, err := svc.repo.LastPublishedID(ctx)
+	if err != nil {
+		svc.log.Error("failed to get last article id", "err", err)
+		return 0, err
+	}
+	return id, nil
 }
 
-func (svc *ArticleService) GetArticle(id int64) (model.Article, error) {
-	cacheKey := fmt.Sprintf("article:detail:%d", id)
-	ctx := context.Background()
-	val, err := cache.RDB.Get(ctx, cacheKey).Result()
+func (svc *ArticleService) GetArticle(ctx context.Context, id int64) (model.Article, error) {
+	cacheKey := cache.PrefixArticleDetail + strconv.FormatInt(id, 10)
+	val, err := svc.cache.Get(ctx, cacheKey)
+	observeCache("article_detail", err)
 	if err == nil {
+		if val == cache.NotFoundMarker {
+			return model.Article{}, ErrArticleNotFound
+		}
 		var article model.Article
 		if jsonErr := json.Unmarshal([]byte(val), &article); jsonErr == nil {
 			return article, nil
 		}
 		svc.log.Warn("failed to unmarshal cached article", "id", id, "err", err)
-	} else if err != redis.Nil {
-		svc.log.Warn("redis error during get", "key", cacheKey, "err", err)
+	} else if !errors.Is(err, cache.ErrMiss) {
+		svc.log.Warn("cache error during get", "key", cacheKey, "err", err)
 	}
 
-	// access db
-	article, err := svc.repo.GetByID(id)
+	// access db, only one query per id at a time
+	v, err, _ := svc.group.Do(cacheKey, func() (any, error) {
+		ctx := context.WithoutCancel(ctx)
+		article, err := svc.repo.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				svc.setMarker(ctx, cacheKey)
+				return nil, ErrArticleNotFound
+			}
+			return nil, err
+		}
+		// update cache
+		svc.setCached(ctx, cacheKey, article, articleDetailTTL)
+		return article, nil
+	})
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return model.Article{}, ErrArticleNotFound
+		if !errors.Is(err, ErrArticleNotFound) {
+			svc.log.Error("failed to get article", "id", id, "err", err)
 		}
-		svc.log.Error("failed to get article", "id", id, "err", err)
 		return model.Article{}, err
 	}
-	// update cache
-	data, marshalErr := json.Marshal(article)
-	if marshalErr == nil {
-		setErr := cache.RDB.Set(ctx, cacheKey, data, time.Hour).Err()
-		if setErr != nil {
-			svc.log.Warn("failed to set cache", "key", cacheKey, "err", setErr)
-		}
-	}
-	return article, nil
+	return v.(model.Article), nil
 }
 
-func (svc *ArticleService) Create(article *model.Article) error {
+func (svc *ArticleService) Create(ctx context.Context, article *model.Article) error {
 	svc.ensureAbstract(article)
-	err := svc.repo.Create(article)
+	ensureStatus(article)
+	err := svc.repo.Create(ctx, article)
 	if err != nil {
 		svc.log.Error("failed to create article", "title", article.Title, "err", err)
 		return err
 	}
+	// the new id may have been cached as not found
+	svc.invalidate(ctx, int64(article.ID))
 	return nil
 }
 
-func (svc *ArticleService) Update(article *model.Article) error {
+// Update replaces the article, an empty status keeps the stored one so
+// clients unaware of drafts do not publish them.
+func (svc *ArticleService) Update(ctx context.Context, article *model.Article) error {
 	svc.ensureAbstract(article)
-	err := svc.repo.Update(article)
+	err := svc.repo.Update(ctx, article)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrArticleNotFound
@@ -103,16 +184,55 @@ func (svc *ArticleService) Update(article *model.Article) error {
 		svc.log.Error("failed to update article", "id", article.ID, "err", err)
 		return err
 	}
-	// delete cache
-	cacheKey := fmt.Sprintf("article:detail:%d", article.ID)
-	if delErr := cache.RDB.Del(context.Background(), cacheKey).Err(); delErr != nil {
-		svc.log.Warn("failed to delete cache after update", "key", cacheKey, "err", delErr)
-	}
+	svc.invalidate(ctx, int64(article.ID))
 	return nil
 }
 
-func (svc *ArticleService) Delete(id int64) error {
-	err := svc.repo.Delete(id)
+// ArticlePatch is a partial update, nil fields are left unchanged.
+type ArticlePatch struct {
+	Title    *string
+	Author   *string
+	Content  *string
+	Abstract *string
+	Status   *string
+}
+
+// Patch applies p to the article id and returns the updated article.
+func (svc *ArticleService) Patch(ctx context.Context, id int64, p ArticlePatch) (model.Article, error) {
+	article, err := svc.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Article{}, ErrArticleNotFound
+		}
+		return model.Article{}, err
+	}
+	if p.Title != nil {
+		article.Title = *p.Title
+	}
+	if p.Author != nil {
+		article.Author = *p.Author
+	}
+	if p.Content != nil {
+		article.Content = *p.Content
+	}
+	if p.Abstract != nil {
+		article.Abstract = *p.Abstract
+	}
+	if p.Status != nil {
+		article.Status = *p.Status
+	}
+	if article.Title == "" || article.Content == "" {
+		return model.Article{}, ErrArticleEmpty
+	}
+	if err := svc.Update(ctx, &article); err != nil {
+		return model.Article{}, err
+	}
+	// re-read for the updated_at set by the trigger
+	return svc.GetArticle(ctx, id)
+}
+
+func (svc *ArticleService) Delete(ctx context.Context, id int64) error {
+	err := svc.repo.Delete(ctx, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrArticleNotFound
@@ -120,14 +240,111 @@ func (svc *ArticleService) Delete(id int64) error {
 		svc.log.Error("failed to delete article", "id", id, "err", err)
 		return err
 	}
-	// delete cache
-	cacheKey := fmt.Sprintf("article:detail:%d", id)
-	if delErr := cache.RDB.Del(context.Background(), cacheKey).Err(); delErr != nil {
-		svc.log.Warn("failed to delete cache after delete", "key", cacheKey, "err", delErr)
-	}
+	svc.invalidate(ctx, id)
 	return nil
 }
 
+// Refresh drops the cached detail of article id, for changes made
+// elsewhere such as a new comment moving its comment_count. Lists are
+// left alone, their comment_count may lag until articleListTTL; bumping
+// the version on every comment would flush all of them.
+func (svc *ArticleService) Refresh(ctx context.Context, id int64) {
+	svc.dropDetail(ctx, id)
+}
+
+// invalidate deletes the detail cache of id and bumps the list version.
+func (svc *ArticleService) invalidate(ctx context.Context, id int64) {
+	svc.dropDetail(ctx, id)
+	svc.bumpListVersion(ctx)
+}
+
+func (svc *ArticleService) dropDetail(ctx context.Context, id int64) {
+	cacheKey := cache.PrefixArticleDetail + strconv.FormatInt(id, 10)
+	if delErr := svc.cache.Del(ctx, cacheKey); delErr != nil {
+		svc.log.Warn("failed to delete cache", "key", cacheKey, "err", delErr)
+	}
+}
+
+// bumpListVersion sets a new list version. A timestamp is used instead of
+// a counter, so a version key lost by eviction never revives old lists.
+func (svc *ArticleService) bumpListVersion(ctx context.Context) int64 {
+	v := time.Now().UnixNano()
+	if err := svc.cache.Set(ctx, cache.KeyArticleListVersion, strconv.FormatInt(v, 10), 0); err != nil {
+		svc.log.Warn("failed to bump list version", "err", err)
+	}
+	return v
+}
+
+// Version changes whenever an article does, caches derived from articles
+// embed it in their keys.
+func (svc *ArticleService) Version(ctx context.Context) int64 {
+	return svc.listVersion(ctx)
+}
+
+// listVersion returns the current list version, creating one if missing.
+func (svc *ArticleService) listVersion(ctx context.Context) int64 {
+	val, err := svc.cache.Get(ctx, cache.KeyArticleListVersion)
+	if err != nil {
+		if !errors.Is(err, cache.ErrMiss) {
+			svc.log.Warn("cache error during get", "key", cache.KeyArticleListVersion, "err", err)
+		}
+		return svc.bumpListVersion(ctx)
+	}
+	v, _ := strconv.ParseInt(val, 10, 64)
+	return v
+}
+
+// observeCache counts a lookup of the named cache as hit, miss or error.
+func observeCache(name string, err error) {
+	if errors.Is(err, cache.ErrMiss) {
+		metrics.ObserveCache(name, false, nil)
+		return
+	}
+	metrics.ObserveCache(name, err == nil, err)
+}
+
+// getCached unmarshals the cached value of key into dst, reports whether it was found.
+func (svc *ArticleService) getCached(ctx context.Context, name, key string, dst any) bool {
+	val, err := svc.cache.Get(ctx, key)
+	observeCache(name, err)
+	if err != nil {
+		if !errors.Is(err, cache.ErrMiss) {
+			svc.log.Warn("cache error during get", "key", key, "err", err)
+		}
+		return false
+	}
+	if err := json.Unmarshal([]byte(val), dst); err != nil {
+		svc.log.Warn("failed to unmarshal cached value", "key", key, "err", err)
+		return false
+	}
+	return true
+}
+
+func (svc *ArticleService) setCached(ctx context.Context, key string, v any, ttl time.Duration) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := svc.cache.Set(ctx, key, string(data), jitter(ttl)); err != nil {
+		svc.log.Warn("failed to set cache", "key", key, "err", err)
+	}
+}
+
+func (svc *ArticleService) setMarker(ctx context.Context, key string) {
+	if err := svc.cache.Set(ctx, key, cache.NotFoundMarker, jitter(articleNotFoundTTL)); err != nil {
+		svc.log.Warn("failed to set cache", "key", key, "err", err)
+	}
+}
+
+// jitter spreads ttl by ±10%, so keys cached together do not expire together.
+func jitter(ttl time.Duration) time.Duration {
+	delta := int64(ttl) / 10
+	if delta <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Int64N(2*delta+1)-delta)
+}
+
 func (svc *ArticleService) ensureAbstract(article *model.Article) {
 	if article.Abstract != "" {
 		return
@@ -140,3 +357,10 @@ func (svc *ArticleService) ensureAbstract(article *model.Article) {
 		article.Abstract = article.Content
 	}
 }
+
+// ensureStatus publishes articles saved without a status.
+func ensureStatus(article *model.Article) {
+	if article.Status == "" {
+		article.Status = model.ArticlePublished
+	}
+}
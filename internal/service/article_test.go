@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gngtwhh/WBlog/internal/cache"
+	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/internal/repository"
+)
+
+// fakeArticleRepo counts db hits, GetByID is slow to let callers pile up.
+type fakeArticleRepo struct {
+	articles map[int64]model.Article
+	gets     atomic.Int32
+	lists    atomic.Int32
+}
+
+func (r *fakeArticleRepo) Create(_ context.Context, a *model.Article) error {
+	a.ID = uint64(len(r.articles) + 1)
+	r.articles[int64(a.ID)] = *a
+	return nil
+}
+
+func (r *fakeArticleRepo) GetByID(_ context.Context, id int64) (model.Article, error) {
+	r.gets.Add(1)
+	time.Sleep(20 * time.Millisecond)
+	a, ok := r.articles[id]
+	if !ok {
+		return model.Article{}, sql.ErrNoRows
+	}
+	return a, nil
+}
+
+func (r *fakeArticleRepo) Update(_ context.Context, a *model.Article) error {
+	updated := *a
+	if updated.Status == "" {
+		updated.Status = r.articles[int64(a.ID)].Status
+	}
+	r.articles[int64(a.ID)] = updated
+	return nil
+}
+
+func (r *fakeArticleRepo) Delete(_ context.Context, id int64) error {
+	delete(r.articles, id)
+	return nil
+}
+
+func (r *fakeArticleRepo) GetList(_ context.Context, _ repository.ArticleQuery) ([]model.Article, error) {
+	r.lists.Add(1)
+	list := make([]model.Article, 0)
+	for _, a := range r.articles {
+		list = append(list, a)
+	}
+	return list, nil
+}
+
+func (r *fakeArticleRepo) Count(_ context.Context, _ repository.ArticleQuery) (int64, error) {
+	return int64(len(r.articles)), nil
+}
+
+func (r *fakeArticleRepo) ListStamps(_ context.Context, _ int64, _ int) ([]model.ArticleStamp, error) {
+	return nil, nil
+}
+
+func (r *fakeArticleRepo) LastPublishedID(_ context.Context) (int64, error) {
+	return 0, nil
+}
+
+func newTestArticleService() (*ArticleService, *fakeArticleRepo) {
+	repo := &fakeArticleRepo{articles: map[int64]model.Article{1: {ID: 1, Title: "hello"}}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewArticleService(repo, cache.NewLRUStore(100), logger), repo
+}
+
+func TestArticleService_CoalescesMisses(t *testing.T) {
+	svc, repo := newTestArticleService()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.GetArticle(ctx, 1); err != nil {
+				t.Errorf("GetArticle: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := repo.gets.Load(); n != 1 {
+		t.Errorf("db queried %d times, want 1", n)
+	}
+}
+
+func TestArticleService_NegativeCache(t *testing.T) {
+	svc, repo := newTestArticleService()
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := svc.GetArticle(ctx, 2); !errors.Is(err, ErrArticleNotFound) {
+			t.Fatalf("err = %v, want %v", err, ErrArticleNotFound)
+		}
+	}
+	if n := repo.gets.Load(); n != 1 {
+		t.Errorf("db queried %d times, want 1", n)
+	}
+
+	// creating the article must drop the not-found marker
+	svc.Create(ctx, &model.Article{Title: "second", Content: "x"})
+	if _, err := svc.GetArticle(ctx, 2); err != nil {
+		t.Errorf("GetArticle after create: %v", err)
+	}
+}
+
+func TestArticleService_ListInvalidation(t *testing.T) {
+	svc, repo := newTestArticleService()
+	ctx := context.Background()
+	svc.ListArticles(ctx, repository.ArticleQuery{Limit: 10})
+	svc.ListArticles(ctx, repository.ArticleQuery{Limit: 10})
+	if n := repo.lists.Load(); n != 1 {
+		t.Fatalf("db listed %d times, want 1", n)
+	}
+
+	svc.Create(ctx, &model.Article{Title: "second", Content: "x"})
+	list, _ := svc.ListArticles(ctx, repository.ArticleQuery{Limit: 10})
+	if len(list) != 2 {
+		t.Errorf("list has %d articles after create, want 2", len(list))
+	}
+	if count, _ := svc.Count(ctx, repository.ArticleQuery{}); count != 2 {
+		t.Errorf("Count = %d, want 2", count)
+	}
+}
+
+// TestArticleService_RefreshKeepsLists checks that Refresh, called on
+// every new comment, drops the detail but not the cached lists.
+func TestArticleService_RefreshKeepsLists(t *testing.T) {
+	svc, repo := newTestArticleService()
+	ctx := context.Background()
+	svc.GetArticle(ctx, 1)
+	svc.ListArticles(ctx, repository.ArticleQuery{Limit: 10})
+
+	svc.Refresh(ctx, 1)
+	svc.GetArticle(ctx, 1)
+	svc.ListArticles(ctx, repository.ArticleQuery{Limit: 10})
+	if n := repo.gets.Load(); n != 2 {
+		t.Errorf("db queried %d times, want 2", n)
+	}
+	if n := repo.lists.Load(); n != 1 {
+		t.Errorf("db listed %d times, want 1", n)
+	}
+}
+
+func TestArticleService_Patch(t *testing.T) {
+	svc, repo := newTestArticleService()
+	ctx := context.Background()
+	repo.articles[1] = model.Article{ID: 1, Title: "hello", Author: "a", Content: "body", Abstract: "abs"}
+
+	title := "hi"
+	got, err := svc.Patch(ctx, 1, ArticlePatch{Title: &title})
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if got.Title != "hi" || got.Content != "body" || got.Author != "a" || got.Abstract != "abs" {
+		t.Errorf("Patch changed absent fields: %+v", got)
+	}
+
+	empty := ""
+	if _, err := svc.Patch(ctx, 1, ArticlePatch{Content: &empty}); !errors.Is(err, ErrArticleEmpty) {
+		t.Errorf("Patch to empty content: err = %v, want ErrArticleEmpty", err)
+	}
+	if _, err := svc.Patch(ctx, 2, ArticlePatch{Title: &title}); !errors.Is(err, ErrArticleNotFound) {
+		t.Errorf("Patch of missing article: err = %v, want ErrArticleNotFound", err)
+	}
+}
+
+func TestArticleService_ListCachedPerQuery(t *testing.T) {
+	svc, repo := newTestArticleService()
+	ctx := context.Background()
+	byViews := repository.ArticleQuery{Sort: repository.SortViews, Limit: 10}
+	svc.ListArticles(ctx, repository.ArticleQuery{Limit: 10})
+	svc.ListArticles(ctx, byViews)
+	svc.ListArticles(ctx, byViews)
+	if n := repo.lists.Load(); n != 2 {
+		t.Errorf("db listed %d times, want 2", n)
+	}
+}
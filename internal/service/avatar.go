@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gngtwhh/WBlog/internal/config"
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/imageutil"
+)
+
+var (
+	ErrInvalidImage  = errcode.New(errcode.UnsupportedFile, "invalid or unsupported image")
+	ErrInvalidAvatar = errcode.New(errcode.InvalidAvatar, "avatar must be uploaded via /api/user/upload-avatar")
+)
+
+// UploadAvatar re-encodes the image into square PNGs of every configured
+// size and sets the largest one as the user's avatar.
+// Files are named {hash}_{size}.png under {upload_dir}/avatars/{uid}/.
+func (svc *UserService) UploadAvatar(ctx context.Context, userID uint64, data []byte) (string, error) {
+	user, err := svc.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+
+	img, mime, err := imageutil.Decode(data)
+	if err != nil {
+		svc.log.Info("rejected avatar upload", "uid", userID, "mime", mime, "err", err)
+		return "", ErrInvalidImage
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:8])
+	uid := strconv.FormatUint(userID, 10)
+	dir := filepath.Join(config.Cfg.GetUploadDir(), "avatars", uid)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		svc.log.Error("failed to create avatar dir", "dir", dir, "err", err)
+		return "", err
+	}
+
+	sizes := config.Cfg.GetAvatarSizes()
+	for _, size := range sizes {
+		out, err := imageutil.EncodePNG(imageutil.Square(img, size))
+		if err != nil {
+			svc.log.Error("failed to encode avatar", "uid", userID, "err", err)
+			return "", err
+		}
+		if err := writeFileAtomic(filepath.Join(dir, avatarName(hash, size)), out); err != nil {
+			svc.log.Error("failed to save avatar", "uid", userID, "err", err)
+			return "", err
+		}
+	}
+
+	user.Avatar = config.Cfg.GetUploadURLPrefix() + "avatars/" + uid + "/" + avatarName(hash, sizes[0])
+	if err := svc.repo.Update(ctx, user); err != nil {
+		svc.log.Error("failed to update avatar", "uid", userID, "err", err)
+		return "", err
+	}
+	svc.removeStaleAvatars(dir, hash)
+	return user.Avatar, nil
+}
+
+// validAvatar reports whether avatar may be set through UpdateProfile:
+// the default avatar or one previously uploaded by this user.
+func validAvatar(userID uint64, avatar string) bool {
+	if avatar == config.Cfg.GetDefaultAvatar() {
+		return true
+	}
+	prefix := fmt.Sprintf("%savatars/%d/", config.Cfg.GetUploadURLPrefix(), userID)
+	name, ok := strings.CutPrefix(avatar, prefix)
+	return ok && name != "" && !strings.ContainsAny(name, "/\\") && !strings.Contains(name, "..")
+}
+
+func avatarName(hash string, size int) string {
+	return fmt.Sprintf("%s_%d.png", hash, size)
+}
+
+func (svc *UserService) removeStaleAvatars(dir, keepHash string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), keepHash+"_") {
+			if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+				svc.log.Warn("failed to remove old avatar", "file", e.Name(), "err", err)
+			}
+		}
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"log/slog"
 
 	"github.com/gngtwhh/WBlog/internal/model"
@@ -22,10 +23,10 @@ func NewCommentService(repo repository.CommentRepository, acFilter *sensitive.AC
 	}
 }
 
-func (s *CommentService) Create(comment *model.Comment) error {
+func (s *CommentService) Create(ctx context.Context, comment *model.Comment) error {
 	// TODO: should send err to frontend
 	comment.Content = s.acFilter.Filter(comment.Content)
-	if err := s.repo.Create(comment); err != nil {
+	if err := s.repo.Create(ctx, comment); err != nil {
 		s.log.Error("failed to create comment",
 			"uid", comment.UserID, "articleid", comment.ArticleID, "err", err)
 		return err
@@ -33,8 +34,27 @@ func (s *CommentService) Create(comment *model.Comment) error {
 	return nil
 }
 
-func (s *CommentService) List(articleID int64, limit, offset int) ([]*model.Comment, error) {
-	comments, err := s.repo.ListByArticleID(articleID, limit, offset)
+// ListAfter lists limit comments of an article after cursor.
+func (s *CommentService) ListAfter(ctx context.Context, articleID int64, cursor *repository.Cursor, limit int) ([]*model.Comment, error) {
+	comments, err := s.repo.ListByArticleIDAfter(ctx, articleID, cursor, limit)
+	if err != nil {
+		s.log.Error("failed to list comments", "err", err)
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (s *CommentService) Count(ctx context.Context, articleID int64) (int64, error) {
+	count, err := s.repo.CountByArticleID(ctx, articleID)
+	if err != nil {
+		s.log.Error("failed to count comments", "articleid", articleID, "err", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *CommentService) List(ctx context.Context, articleID int64, limit, offset int) ([]*model.Comment, error) {
+	comments, err := s.repo.ListByArticleID(ctx, articleID, limit, offset)
 	if err != nil {
 		s.log.Error("failed to list articles", "err", err)
 		return nil, err
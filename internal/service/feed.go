@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gngtwhh/WBlog/internal/cache"
+	"github.com/gngtwhh/WBlog/internal/config"
+	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/internal/repository"
+	"github.com/gngtwhh/WBlog/pkg/feed"
+	"golang.org/x/sync/singleflight"
+)
+
+const feedTTL = time.Hour
+
+// FeedService generates the feeds of the newest published articles.
+// Generated feeds are cached under the article list version, so any
+// article change regenerates them.
+type FeedService struct {
+	articles *ArticleService
+	cache    cache.Store
+	group    singleflight.Group
+	log      *slog.Logger
+}
+
+func NewFeedService(articles *ArticleService, store cache.Store, logger *slog.Logger) *FeedService {
+	return &FeedService{
+		articles: articles,
+		cache:    store,
+		log:      logger.With("component", "feed_service"),
+	}
+}
+
+// Feed is a generated feed. Modified is the last article change, for
+// Last-Modified; it is never earlier than the change but may be later,
+// when the list version was lost and had to be renewed.
+type Feed struct {
+	Body     []byte    `json:"body"`
+	Modified time.Time `json:"modified"`
+}
+
+// Render returns the feed in format, links are made absolute by baseURL
+// and feedPath is the path the feed is served at. baseURL must be the
+// configured site URL, it is not part of the cache key.
+func (svc *FeedService) Render(ctx context.Context, format feed.Format, baseURL, feedPath string) (*Feed, error) {
+	version := svc.articles.Version(ctx)
+	cacheKey := fmt.Sprintf("%sv%d:%s", cache.PrefixFeed, version, format)
+	val, err := svc.cache.Get(ctx, cacheKey)
+	observeCache("feed", err)
+	if err == nil {
+		var f Feed
+		if jsonErr := json.Unmarshal([]byte(val), &f); jsonErr == nil {
+			return &f, nil
+		}
+		svc.log.Warn("failed to unmarshal cached feed", "key", cacheKey)
+	} else if !errors.Is(err, cache.ErrMiss) {
+		svc.log.Warn("cache error during get", "key", cacheKey, "err", err)
+	}
+
+	v, err, _ := svc.group.Do(cacheKey, func() (any, error) {
+		ctx := context.WithoutCancel(ctx)
+		body, err := svc.generate(ctx, format, baseURL, feedPath)
+		if err != nil {
+			return nil, err
+		}
+		// the version is the unix nano time of the last change
+		f := &Feed{Body: body, Modified: time.Unix(0, version).UTC()}
+		if data, err := json.Marshal(f); err == nil {
+			if err := svc.cache.Set(ctx, cacheKey, string(data), jitter(feedTTL)); err != nil {
+				svc.log.Warn("failed to set cache", "key", cacheKey, "err", err)
+			}
+		}
+		return f, nil
+	})
+	if err != nil {
+		svc.log.Error("failed to render feed", "format", format, "err", err)
+		return nil, err
+	}
+	return v.(*Feed), nil
+}
+
+func (svc *FeedService) generate(ctx context.Context, format feed.Format, baseURL, feedPath string) ([]byte, error) {
+	articles, err := svc.articles.ListArticles(ctx, repository.ArticleQuery{
+		Status:      model.ArticlePublished,
+		Limit:       config.Cfg.GetFeedSize(),
+		WithContent: config.Cfg.Feed.FullContent,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	f := &feed.Feed{
+		Title:       config.Cfg.GetSiteTitle(),
+		Description: config.Cfg.Site.Description,
+		Link:        baseURL + "/",
+		FeedURL:     baseURL + feedPath,
+		Language:    config.Cfg.GetDefaultLocale(),
+	}
+	for _, a := range articles {
+		link := baseURL + "/article/" + strconv.FormatUint(a.ID, 10)
+		f.Items = append(f.Items, feed.Item{
+			ID:        link,
+			Title:     a.Title,
+			Link:      link,
+			Author:    a.Author,
+			Summary:   a.Abstract,
+			Content:   a.Content, // empty unless full content is configured
+			Published: a.CreatedAt,
+			Updated:   a.UpdatedAt,
+		})
+		if a.UpdatedAt.After(f.Updated) {
+			f.Updated = a.UpdatedAt
+		}
+	}
+
+	return feed.Encode(format, f)
+}
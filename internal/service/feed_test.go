@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/gngtwhh/WBlog/internal/cache"
+	"github.com/gngtwhh/WBlog/internal/config"
+	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/pkg/feed"
+)
+
+func TestFeedService_RegeneratedOnChange(t *testing.T) {
+	config.Cfg = &config.Config{}
+	articles, repo := newTestArticleService()
+	svc := NewFeedService(articles, cache.NewLRUStore(100), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	render := func() string {
+		f, err := svc.Render(ctx, feed.RSS, "https://blog.example.com", "/feed.xml")
+		if err != nil {
+			t.Fatalf("Render: %v", err)
+		}
+		return string(f.Body)
+	}
+	if body := render(); !strings.Contains(body, "https://blog.example.com/article/1") {
+		t.Fatalf("feed lacks the article link:\n%s", body)
+	}
+	render()
+	if n := repo.lists.Load(); n != 1 {
+		t.Errorf("db listed %d times, want 1", n)
+	}
+
+	articles.Create(ctx, &model.Article{Title: "second", Content: "x"})
+	if body := render(); !strings.Contains(body, "second") {
+		t.Errorf("feed not regenerated after create:\n%s", body)
+	}
+}
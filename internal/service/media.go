@@ -0,0 +1,221 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/internal/repository"
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/imageutil"
+	"github.com/gngtwhh/WBlog/pkg/storage"
+)
+
+var (
+	ErrMediaNotFound        = errcode.New(errcode.MediaNotFound, "media not found")
+	ErrUnsupportedMediaType = errcode.New(errcode.UnsupportedFile, "unsupported media type")
+)
+
+const thumbSize = 320
+
+// attachment types allowed besides images, detected from content
+var attachmentTypes = map[string]string{
+	"application/pdf":           ".pdf",
+	"application/zip":           ".zip",
+	"application/x-gzip":        ".gz",
+	"text/plain; charset=utf-8": ".txt",
+}
+
+var imageExts = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type MediaService struct {
+	repo  repository.MediaRepository
+	store storage.Storage
+	log   *slog.Logger
+}
+
+func NewMediaService(repo repository.MediaRepository, store storage.Storage, logger *slog.Logger) *MediaService {
+	return &MediaService{
+		repo:  repo,
+		store: store,
+		log:   logger.With("component", "media_service"),
+	}
+}
+
+// Upload stores data and records it, identical content is stored only
+// once and the existing record is returned.
+func (svc *MediaService) Upload(ctx context.Context, uploaderID uint64, filename string, data []byte) (*model.Media, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if existing, err := svc.repo.GetByHash(hash); err == nil {
+		return svc.withURLs(existing), nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		svc.log.Error("failed to query media by hash", "err", err)
+		return nil, err
+	}
+
+	media := &model.Media{
+		Hash:       hash,
+		Filename:   cleanFilename(filename),
+		Size:       int64(len(data)),
+		UploaderID: uploaderID,
+	}
+
+	var thumb []byte
+	contentType := http.DetectContentType(data)
+	if ext, ok := imageExts[contentType]; ok {
+		img, _, err := imageutil.Decode(data)
+		if err != nil {
+			svc.log.Info("rejected media upload", "mime", contentType, "err", err)
+			return nil, ErrUnsupportedMediaType
+		}
+		media.MIME = contentType
+		media.Width, media.Height = img.Bounds().Dx(), img.Bounds().Dy()
+		media.StorageKey = mediaKey(hash, ext)
+		thumb, err = imageutil.EncodePNG(imageutil.Fit(img, thumbSize, thumbSize))
+		if err != nil {
+			svc.log.Error("failed to encode thumbnail", "err", err)
+			return nil, err
+		}
+		media.ThumbKey = "media/thumbs/" + hash[:2] + "/" + hash + ".png"
+	} else if ext, ok := attachmentTypes[contentType]; ok {
+		media.MIME, _, _ = mime.ParseMediaType(contentType)
+		media.StorageKey = mediaKey(hash, ext)
+	} else {
+		return nil, ErrUnsupportedMediaType
+	}
+
+	// keys derive from the hash, a concurrent upload of the same content
+	// may have stored them already; only objects created here are removed
+	var created []string
+	put := func(key string, body []byte, contentType string) error {
+		existed := svc.exists(ctx, key)
+		if err := svc.store.Put(ctx, key, bytes.NewReader(body), contentType); err != nil {
+			return err
+		}
+		if !existed {
+			created = append(created, key)
+		}
+		return nil
+	}
+	if err := put(media.StorageKey, data, media.MIME); err != nil {
+		svc.log.Error("failed to store media", "key", media.StorageKey, "err", err)
+		return nil, err
+	}
+	if thumb != nil {
+		if err := put(media.ThumbKey, thumb, "image/png"); err != nil {
+			svc.log.Error("failed to store thumbnail", "key", media.ThumbKey, "err", err)
+			svc.removeObjects(ctx, created...)
+			return nil, err
+		}
+	}
+	if err := svc.repo.Create(media); err != nil {
+		// lost the race on UNIQUE(hash), the winner's record owns the objects
+		if existing, getErr := svc.repo.GetByHash(hash); getErr == nil {
+			return svc.withURLs(existing), nil
+		}
+		svc.log.Error("failed to create media", "hash", hash, "err", err)
+		svc.removeObjects(ctx, created...)
+		return nil, err
+	}
+	return svc.withURLs(media), nil
+}
+
+func (svc *MediaService) List(limit, offset int) ([]*model.Media, int64, error) {
+	list, err := svc.repo.GetList(limit, offset)
+	if err != nil {
+		svc.log.Error("failed to list media", "err", err)
+		return nil, 0, err
+	}
+	count, err := svc.repo.Count()
+	if err != nil {
+		svc.log.Error("failed to count media", "err", err)
+		return nil, 0, err
+	}
+	for _, m := range list {
+		svc.withURLs(m)
+	}
+	return list, count, nil
+}
+
+func (svc *MediaService) Delete(ctx context.Context, id uint64) error {
+	media, err := svc.repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMediaNotFound
+		}
+		svc.log.Error("failed to get media", "id", id, "err", err)
+		return err
+	}
+	if err := svc.repo.Delete(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMediaNotFound
+		}
+		svc.log.Error("failed to delete media", "id", id, "err", err)
+		return err
+	}
+	svc.removeObjects(ctx, media.StorageKey, media.ThumbKey)
+	return nil
+}
+
+func (svc *MediaService) withURLs(m *model.Media) *model.Media {
+	m.URL = svc.store.URL(m.StorageKey)
+	if m.ThumbKey != "" {
+		m.ThumbURL = svc.store.URL(m.ThumbKey)
+	}
+	return m
+}
+
+// exists reports whether key is stored, unknown counts as stored so it
+// is never removed by mistake.
+func (svc *MediaService) exists(ctx context.Context, key string) bool {
+	rc, err := svc.store.Open(ctx, key)
+	if err != nil {
+		return !errors.Is(err, storage.ErrNotFound)
+	}
+	rc.Close()
+	return true
+}
+
+func (svc *MediaService) removeObjects(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := svc.store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			svc.log.Warn("failed to remove media object", "key", key, "err", err)
+		}
+	}
+}
+
+// mediaKey spreads objects over 256 directories by hash prefix.
+func mediaKey(hash, ext string) string {
+	return "media/" + hash[:2] + "/" + hash + ext
+}
+
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = path.Base(name)
+	if name == "." || name == "/" {
+		return "unnamed"
+	}
+	if r := []rune(name); len(r) > 200 {
+		name = string(r[:200])
+	}
+	return name
+}
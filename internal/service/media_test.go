@@ -0,0 +1,65 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/internal/repository"
+	"github.com/gngtwhh/WBlog/pkg/storage"
+)
+
+// racingMediaRepo loses every insert to a concurrent upload of the same
+// content, the winner's record shows up right after.
+type racingMediaRepo struct {
+	repository.MediaRepository
+	winner *model.Media
+	seen   bool
+}
+
+func (r *racingMediaRepo) GetByHash(hash string) (*model.Media, error) {
+	if !r.seen {
+		return nil, sql.ErrNoRows
+	}
+	m := *r.winner
+	return &m, nil
+}
+
+func (r *racingMediaRepo) Create(media *model.Media) error {
+	r.seen = true
+	r.winner.Hash, r.winner.StorageKey = media.Hash, media.StorageKey
+	return errors.New("UNIQUE constraint failed: media.hash")
+}
+
+func TestMediaService_UploadLosesRace(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemory("/uploads/")
+	repo := &racingMediaRepo{winner: &model.Media{ID: 7}}
+	svc := NewMediaService(repo, store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	data := []byte("hello, world\n")
+	sum := sha256.Sum256(data)
+	key := mediaKey(hex.EncodeToString(sum[:]), ".txt")
+	// the winner stored the object before our insert failed
+	store.Put(ctx, key, bytes.NewReader(data), "text/plain")
+
+	got, err := svc.Upload(ctx, 2, "a.txt", data)
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if got.ID != 7 {
+		t.Errorf("got media %d, want the winner's record 7", got.ID)
+	}
+	rc, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("winner's object was removed: %v", err)
+	}
+	rc.Close()
+}
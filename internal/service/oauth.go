@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/gngtwhh/WBlog/internal/config"
+	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/internal/repository"
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/oauth"
+	"github.com/gngtwhh/WBlog/pkg/utils"
+)
+
+var (
+	ErrProviderNotFound = errcode.New(errcode.OAuthProviderNotFound, "oauth provider not found")
+	ErrOAuthFailed      = errcode.New(errcode.OAuthFailed, "oauth login failed")
+)
+
+type OAuthService struct {
+	providers  map[string]*oauth.Provider
+	users      repository.UserRepository
+	identities repository.IdentityRepository
+	log        *slog.Logger
+}
+
+func NewOAuthService(providers []*oauth.Provider, users repository.UserRepository,
+	identities repository.IdentityRepository, logger *slog.Logger) *OAuthService {
+	m := make(map[string]*oauth.Provider, len(providers))
+	for _, p := range providers {
+		m[p.Name()] = p
+	}
+	return &OAuthService{
+		providers:  m,
+		users:      users,
+		identities: identities,
+		log:        logger.With("component", "oauth_service"),
+	}
+}
+
+// Providers returns the names of configured providers, sorted so the
+// login page lists them in a stable order.
+func (svc *OAuthService) Providers() []string {
+	names := make([]string, 0, len(svc.providers))
+	for name := range svc.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AuthURL starts a login, the returned state and verifier must be
+// stored by the caller and passed back to Callback.
+func (svc *OAuthService) AuthURL(provider string) (authURL, state, verifier string, err error) {
+	p, ok := svc.providers[provider]
+	if !ok {
+		return "", "", "", ErrProviderNotFound
+	}
+	state = oauth.GenerateState()
+	verifier = oauth.GenerateVerifier()
+	return p.AuthCodeURL(state, verifier), state, verifier, nil
+}
+
+// Callback finishes a login: exchanges the code, maps the userinfo to a
+// local user (creating and linking one on first login) and issues a JWT.
+func (svc *OAuthService) Callback(ctx context.Context, provider, code, verifier string) (*model.User, string, error) {
+	p, ok := svc.providers[provider]
+	if !ok {
+		return nil, "", ErrProviderNotFound
+	}
+	token, err := p.Exchange(ctx, code, verifier)
+	if err != nil {
+		svc.log.Warn("oauth code exchange failed", "provider", provider, "err", err)
+		return nil, "", ErrOAuthFailed
+	}
+	info, err := p.UserInfo(ctx, token)
+	if err != nil {
+		svc.log.Warn("oauth userinfo failed", "provider", provider, "err", err)
+		return nil, "", ErrOAuthFailed
+	}
+
+	user, err := svc.findOrCreateUser(ctx, provider, info)
+	if err != nil {
+		return nil, "", err
+	}
+
+	jwtToken, err := utils.GenToken(user.ID, user.Username, user.Role, user.Locale, config.Cfg.GetJwtDuration())
+	if err != nil {
+		svc.log.Error("failed to generate token", "uid", user.ID, "err", err)
+		return nil, "", err
+	}
+	user.Password = ""
+	return user, jwtToken, nil
+}
+
+func (svc *OAuthService) findOrCreateUser(ctx context.Context, provider string, info *oauth.UserInfo) (*model.User, error) {
+	identity, err := svc.identities.GetByProviderSubject(provider, info.Subject)
+	if err == nil {
+		user, err := svc.users.GetByID(ctx, identity.UserID)
+		if err != nil {
+			svc.log.Error("linked user of identity missing", "identity", identity.ID, "err", err)
+			return nil, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		svc.log.Error("failed to query identity", "provider", provider, "err", err)
+		return nil, err
+	}
+
+	// first login: create a local user, password is random and unusable
+	hashedPwd, err := utils.HashPassword(oauth.GenerateState())
+	if err != nil {
+		svc.log.Error("failed to hash password", "err", err)
+		return nil, errors.New("internal error: hashing password failed")
+	}
+	username, err := svc.availableUsername(ctx, provider, info)
+	if err != nil {
+		return nil, err
+	}
+	nickname := info.Nickname
+	if nickname == "" {
+		nickname = username
+	}
+	avatar := info.Avatar
+	if avatar == "" {
+		avatar = config.Cfg.GetDefaultAvatar()
+	}
+	user := &model.User{
+		Username: username,
+		Password: hashedPwd,
+		Nickname: nickname,
+		Avatar:   avatar,
+		Role:     model.RoleUser,
+		Status:   model.StatusNormal,
+	}
+	identity = &model.UserIdentity{
+		Provider: provider,
+		Subject:  info.Subject,
+		Email:    info.Email,
+	}
+	if err := svc.identities.CreateWithUser(ctx, user, identity); err != nil {
+		// lost the race to a concurrent first login of the same identity,
+		// its user is the one to log in
+		if winner, gerr := svc.identities.GetByProviderSubject(provider, info.Subject); gerr == nil {
+			return svc.users.GetByID(ctx, winner.UserID)
+		}
+		svc.log.Error("failed to create oauth user", "username", username, "provider", provider, "err", err)
+		return nil, err
+	}
+	svc.log.Info("linked new oauth identity", "uid", user.ID, "provider", provider)
+	return user, nil
+}
+
+// availableUsername picks a local username not used yet, preferring the
+// provider username and falling back to "{provider}_{subject}".
+func (svc *OAuthService) availableUsername(ctx context.Context, provider string, info *oauth.UserInfo) (string, error) {
+	base := strings.TrimSpace(info.Username)
+	if base == "" {
+		base = provider + "_" + info.Subject
+	}
+	candidates := []string{base, provider + "_" + base}
+	for i := 2; i <= 10; i++ {
+		candidates = append(candidates, fmt.Sprintf("%s_%s%d", provider, base, i))
+	}
+
+	for _, name := range candidates {
+		_, err := svc.users.GetByUsername(ctx, name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return name, nil
+		}
+		if err != nil {
+			svc.log.Error("failed to check username existence", "err", err)
+			return "", err
+		}
+	}
+	return provider + "_" + oauth.GenerateState()[:12], nil
+}
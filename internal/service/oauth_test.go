@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/gngtwhh/WBlog/internal/config"
+	"github.com/gngtwhh/WBlog/internal/model"
+	"github.com/gngtwhh/WBlog/internal/repository"
+	"github.com/gngtwhh/WBlog/pkg/oauth"
+)
+
+// racingIdentityRepo loses every insert to a concurrent first login of
+// the same identity, the winner's link shows up right after.
+type racingIdentityRepo struct {
+	repository.IdentityRepository
+	winner *model.UserIdentity
+	seen   bool
+}
+
+func (r *racingIdentityRepo) GetByProviderSubject(provider, subject string) (*model.UserIdentity, error) {
+	if !r.seen {
+		return nil, sql.ErrNoRows
+	}
+	return r.winner, nil
+}
+
+func (r *racingIdentityRepo) CreateWithUser(_ context.Context, _ *model.User, _ *model.UserIdentity) error {
+	r.seen = true
+	return errors.New("UNIQUE constraint failed: user_identities.provider, user_identities.subject")
+}
+
+type usersByID struct {
+	repository.UserRepository
+	users map[uint64]*model.User
+}
+
+func (r *usersByID) GetByUsername(context.Context, string) (*model.User, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *usersByID) GetByID(_ context.Context, id uint64) (*model.User, error) {
+	if u, ok := r.users[id]; ok {
+		return u, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func TestOAuthService_FirstLoginLosesRace(t *testing.T) {
+	config.Cfg = &config.Config{}
+	users := &usersByID{users: map[uint64]*model.User{9: {ID: 9, Username: "octocat"}}}
+	identities := &racingIdentityRepo{winner: &model.UserIdentity{ID: 1, UserID: 9, Provider: "github", Subject: "42"}}
+	svc := NewOAuthService(nil, users, identities, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	user, err := svc.findOrCreateUser(context.Background(), "github", &oauth.UserInfo{Subject: "42", Username: "octocat"})
+	if err != nil {
+		t.Fatalf("findOrCreateUser: %v", err)
+	}
+	if user.ID != 9 {
+		t.Errorf("got user %d, want the winner's user 9", user.ID)
+	}
+}
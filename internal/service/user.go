@@ -11,30 +11,35 @@ import (
 	"github.com/gngtwhh/WBlog/internal/config"
 	"github.com/gngtwhh/WBlog/internal/model"
 	"github.com/gngtwhh/WBlog/internal/repository"
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/i18n"
 	"github.com/gngtwhh/WBlog/pkg/utils"
 )
 
 var (
-	ErrUserNotFound   = errors.New("user not found")
-	ErrUserExists     = errors.New("username already exists")
-	ErrAuthFailed     = errors.New("username or password is incorrect")
-	ErrInvalidOldPass = errors.New("invalid old password")
+	ErrUserNotFound   = errcode.New(errcode.UserNotFound, "user not found")
+	ErrUserExists     = errcode.New(errcode.UserExists, "username already exists")
+	ErrAuthFailed     = errcode.New(errcode.AuthFailed, "username or password is incorrect")
+	ErrInvalidOldPass = errcode.New(errcode.WrongOldPass, "invalid old password")
+	ErrInvalidLocale  = errcode.New(errcode.ParamError, "unsupported locale")
 )
 
 type UserService struct {
-	repo repository.UserRepository
-	log  *slog.Logger
+	repo  repository.UserRepository
+	cache cache.Store
+	log   *slog.Logger
 }
 
-func NewUserService(repo repository.UserRepository, logger *slog.Logger) *UserService {
+func NewUserService(repo repository.UserRepository, store cache.Store, logger *slog.Logger) *UserService {
 	return &UserService{
-		repo: repo,
-		log:  logger.With("component", "user_service"),
+		repo:  repo,
+		cache: store,
+		log:   logger.With("component", "user_service"),
 	}
 }
 
-func (svc *UserService) Register(user *model.User) error {
-	existUser, err := svc.repo.GetByUsername(user.Username)
+func (svc *UserService) Register(ctx context.Context, user *model.User) error {
+	existUser, err := svc.repo.GetByUsername(ctx, user.Username)
 	if err == nil {
 		if existUser != nil {
 			return ErrUserExists
@@ -48,19 +53,18 @@ func (svc *UserService) Register(user *model.User) error {
 		return errors.New("internal error: hashing password failed")
 	}
 	user.Password = hashedPwd
-	// TODO: avatar path -> Config/os.env
 	if user.Avatar == "" {
-		user.Avatar = "/static/default_avatar.png"
+		user.Avatar = config.Cfg.GetDefaultAvatar()
 	}
-	if err := svc.repo.Create(user); err != nil {
+	if err := svc.repo.Create(ctx, user); err != nil {
 		svc.log.Error("failed to create user", "username", user.Username, "err", err)
 		return err
 	}
 	return nil
 }
 
-func (svc *UserService) Login(username, password string) (*model.User, string, error) {
-	user, err := svc.repo.GetByUsername(username)
+func (svc *UserService) Login(ctx context.Context, username, password string) (*model.User, string, error) {
+	user, err := svc.repo.GetByUsername(ctx, username)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, "", ErrAuthFailed
@@ -72,8 +76,7 @@ func (svc *UserService) Login(username, password string) (*model.User, string, e
 	if !utils.CheckPassword(user.Password, password) {
 		return nil, "", ErrAuthFailed
 	}
-	// TODO: issuer should be load by Config/os.env
-	token, err := utils.GenToken(user.ID, user.Username, user.Role, config.Cfg.GetJwtDuration(), "WBLOG")
+	token, err := utils.GenToken(user.ID, user.Username, user.Role, user.Locale, config.Cfg.GetJwtDuration())
 	if err != nil {
 		svc.log.Error("failed to generate token", "uid", user.ID, "err", err)
 		return nil, "", err
@@ -82,8 +85,8 @@ func (svc *UserService) Login(username, password string) (*model.User, string, e
 	return user, token, nil
 }
 
-func (svc *UserService) GetProfile(id uint64) (*model.User, error) {
-	user, err := svc.repo.GetByID(id)
+func (svc *UserService) GetProfile(ctx context.Context, id uint64) (*model.User, error) {
+	user, err := svc.repo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
@@ -97,8 +100,8 @@ func (svc *UserService) GetProfile(id uint64) (*model.User, error) {
 	return user, nil
 }
 
-func (svc *UserService) UpdateProfile(inputUser *model.User) error {
-	user, err := svc.repo.GetByID(inputUser.ID)
+func (svc *UserService) UpdateProfile(ctx context.Context, inputUser *model.User) error {
+	user, err := svc.repo.GetByID(ctx, inputUser.ID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrUserNotFound
@@ -111,15 +114,26 @@ func (svc *UserService) UpdateProfile(inputUser *model.User) error {
 		user.Nickname = inputUser.Nickname
 		needUpdate = true
 	}
-	// TODO: file upload/link check
 	if inputUser.Avatar != "" && inputUser.Avatar != user.Avatar {
+		if !validAvatar(user.ID, inputUser.Avatar) {
+			return ErrInvalidAvatar
+		}
 		user.Avatar = inputUser.Avatar
 		needUpdate = true
 	}
+	if inputUser.Locale != "" && inputUser.Locale != user.Locale {
+		locale, ok := i18n.Supported(inputUser.Locale)
+		if !ok {
+			return ErrInvalidLocale
+		}
+		// the token carries the locale, see ReissueToken
+		user.Locale = locale
+		needUpdate = true
+	}
 	if !needUpdate {
 		return nil
 	}
-	if err := svc.repo.Update(user); err != nil {
+	if err := svc.repo.Update(ctx, user); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrUserNotFound
 		}
@@ -129,8 +143,27 @@ func (svc *UserService) UpdateProfile(inputUser *model.User) error {
 	return nil
 }
 
-func (svc *UserService) ChangePassword(userID uint64, oldPassword, newPassword string) error {
-	user, err := svc.repo.GetByID(userID)
+// ReissueToken issues a new token for the user from the stored record,
+// so that profile fields the token carries, like the locale, apply at
+// once. It expires after expires, the rest of the current token's life.
+func (svc *UserService) ReissueToken(ctx context.Context, id uint64, expires time.Duration) (string, error) {
+	user, err := svc.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	token, err := utils.GenToken(user.ID, user.Username, user.Role, user.Locale, expires)
+	if err != nil {
+		svc.log.Error("failed to generate token", "uid", user.ID, "err", err)
+		return "", err
+	}
+	return token, nil
+}
+
+func (svc *UserService) ChangePassword(ctx context.Context, userID uint64, oldPassword, newPassword string) error {
+	user, err := svc.repo.GetByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrUserNotFound
@@ -148,14 +181,14 @@ func (svc *UserService) ChangePassword(userID uint64, oldPassword, newPassword s
 	}
 	user.Password = hashedPwd
 
-	if err := svc.repo.Update(user); err != nil {
+	if err := svc.repo.Update(ctx, user); err != nil {
 		svc.log.Error("failed to update password", "uid", user.ID, "err", err)
 		return err
 	}
 	return nil
 }
 
-func (svc *UserService) Logout(tokenStr string, exp int64) error {
+func (svc *UserService) Logout(ctx context.Context, tokenStr string, exp int64) error {
 	now := time.Now()
 	expTime := time.Unix(exp, 0)
 	if now.After(expTime) {
@@ -164,7 +197,7 @@ func (svc *UserService) Logout(tokenStr string, exp int64) error {
 	duration := expTime.Sub(now)
 
 	key := cache.PrefixJWTBlacklist + tokenStr
-	err := cache.RDB.Set(context.Background(), key, "1", duration).Err()
+	err := svc.cache.Set(ctx, key, "1", duration)
 	if err != nil {
 		svc.log.Error("failed to add token to blacklist", "key", key, "err", err)
 		return err
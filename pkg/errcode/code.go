@@ -1,36 +1,111 @@
 package errcode
 
+import (
+	"net/http"
+	"sort"
+)
+
 const (
-	Success     = 0
-	ServerError = 10001
-	ParamError  = 10002
-	NotFound    = 10003
+	Success         = 0
+	ServerError     = 10001
+	ParamError      = 10002
+	NotFound        = 10003
+	CSRFInvalid     = 10004
+	FileTooLarge    = 10005
+	UnsupportedFile = 10006
+	Forbidden       = 10007
+	TooManyRequests = 10008
+	BodyTooLarge    = 10009
+	Unavailable     = 10010
 
 	// User (20000 - 29999)
-	UserExists   = 20001
-	UserNotFound = 20002
-	AuthFailed   = 20003
-	TokenInvalid = 20004
+	UserExists    = 20001
+	UserNotFound  = 20002
+	AuthFailed    = 20003
+	TokenInvalid  = 20004
+	Unauthorized  = 20007 // no token, or not a bearer token
+	WrongOldPass  = 20008
+	InvalidAvatar = 20009
+
+	OAuthProviderNotFound = 20005
+	OAuthFailed           = 20006
 
 	// Article (30000 - 39999)
 	ArticleNotFound = 30001
+	ArticleEmpty    = 30002
+
+	// Media (40000 - 49999)
+	MediaNotFound = 40001
 )
 
-// TODO: International sufficiency
-// code msg
+// code msg, used when pkg/i18n has no catalog entry for the code
 var msgFlags = map[int]string{
-	Success:     "ok",
-	ServerError: "系统内部错误，请稍后再试",
-	ParamError:  "请求参数错误",
-	NotFound:    "资源不存在",
+	Success:         "ok",
+	ServerError:     "系统内部错误，请稍后再试",
+	ParamError:      "请求参数错误",
+	NotFound:        "资源不存在",
+	CSRFInvalid:     "请求校验失败，请刷新页面后重试",
+	FileTooLarge:    "上传文件过大",
+	UnsupportedFile: "不支持的文件类型",
+	Forbidden:       "没有权限执行此操作",
+	TooManyRequests: "请求过于频繁，请稍后再试",
+	BodyTooLarge:    "请求内容过大",
+	Unavailable:     "服务暂时不可用，请稍后再试",
 
 	UserExists:   "用户已存在",
 	UserNotFound: "用户不存在",
 
-	AuthFailed:   "用户名或密码错误",
-	TokenInvalid: "登录已过期，请重新登录",
+	AuthFailed:    "用户名或密码错误",
+	TokenInvalid:  "登录已过期，请重新登录",
+	Unauthorized:  "请先登录",
+	WrongOldPass:  "原密码错误",
+	InvalidAvatar: "头像必须通过上传接口设置",
+
+	OAuthProviderNotFound: "不支持的第三方登录方式",
+	OAuthFailed:           "第三方登录失败，请重试",
 
 	ArticleNotFound: "文章不存在",
+	ArticleEmpty:    "标题和内容不能为空",
+
+	MediaNotFound: "文件不存在",
+}
+
+// httpStatus maps codes to HTTP status, codes not listed are 500.
+var httpStatus = map[int]int{
+	Success:         http.StatusOK,
+	ParamError:      http.StatusBadRequest,
+	NotFound:        http.StatusNotFound,
+	CSRFInvalid:     http.StatusForbidden,
+	FileTooLarge:    http.StatusRequestEntityTooLarge,
+	UnsupportedFile: http.StatusUnsupportedMediaType,
+	Forbidden:       http.StatusForbidden,
+	TooManyRequests: http.StatusTooManyRequests,
+	BodyTooLarge:    http.StatusRequestEntityTooLarge,
+	Unavailable:     http.StatusServiceUnavailable,
+
+	UserExists:    http.StatusConflict,
+	UserNotFound:  http.StatusNotFound,
+	AuthFailed:    http.StatusUnauthorized,
+	TokenInvalid:  http.StatusUnauthorized,
+	Unauthorized:  http.StatusUnauthorized,
+	WrongOldPass:  http.StatusBadRequest,
+	InvalidAvatar: http.StatusBadRequest,
+
+	OAuthProviderNotFound: http.StatusNotFound,
+	OAuthFailed:           http.StatusUnauthorized,
+
+	ArticleNotFound: http.StatusNotFound,
+	ArticleEmpty:    http.StatusBadRequest,
+
+	MediaNotFound: http.StatusNotFound,
+}
+
+// HTTPStatus returns the HTTP status replied with code.
+func HTTPStatus(code int) int {
+	if status, ok := httpStatus[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
 }
 
 func GetMsg(code int) string {
@@ -40,3 +115,13 @@ func GetMsg(code int) string {
 	}
 	return msgFlags[ServerError]
 }
+
+// Codes returns every defined code in ascending order.
+func Codes() []int {
+	codes := make([]int, 0, len(msgFlags))
+	for code := range msgFlags {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes
+}
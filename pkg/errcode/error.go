@@ -0,0 +1,50 @@
+package errcode
+
+import "errors"
+
+// Error carries a code through the service layer, so handlers can reply
+// without knowing every error a service returns.
+type Error struct {
+	Code int
+	Msg  string // reply message, empty uses the default message of Code
+	Err  error  // cause, only logged
+	Data any    // reply data, e.g. field errors of a bad request
+}
+
+// New returns an error replying code, desc describes it in logs.
+func New(code int, desc string) *Error {
+	return &Error{Code: code, Err: errors.New(desc)}
+}
+
+// Wrap attaches code to err.
+func Wrap(code int, err error) *Error {
+	return &Error{Code: code, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Message()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Message returns the reply message.
+func (e *Error) Message() string {
+	if e.Msg != "" {
+		return e.Msg
+	}
+	return GetMsg(e.Code)
+}
+
+// From returns the *Error in err's chain, or a ServerError wrapping err.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Wrap(ServerError, err)
+}
@@ -0,0 +1,84 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() *Feed {
+	published := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	return &Feed{
+		Title:    "WBlog",
+		Link:     "https://blog.example.com/",
+		FeedURL:  "https://blog.example.com/feed.xml",
+		Language: "zh-CN",
+		Updated:  published.Add(time.Hour),
+		Items: []Item{{
+			ID:        "https://blog.example.com/article/1",
+			Title:     "a < b",
+			Link:      "https://blog.example.com/article/1",
+			Author:    "bob",
+			Summary:   "short",
+			Content:   "long & full",
+			Published: published,
+			Updated:   published.Add(time.Hour),
+		}},
+	}
+}
+
+func TestEncodeXML(t *testing.T) {
+	for _, format := range []Format{RSS, Atom} {
+		body, err := Encode(format, testFeed())
+		if err != nil {
+			t.Fatalf("Encode(%s): %v", format, err)
+		}
+		// well-formed and escaped
+		var doc struct{}
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			t.Errorf("Encode(%s) is not valid XML: %v\n%s", format, err, body)
+		}
+		for _, want := range []string{"a &lt; b", "long &amp; full", "https://blog.example.com/article/1"} {
+			if !strings.Contains(string(body), want) {
+				t.Errorf("Encode(%s) lacks %q:\n%s", format, want, body)
+			}
+		}
+	}
+
+	body, _ := Encode(RSS, testFeed())
+	if !strings.Contains(string(body), "<pubDate>Wed, 01 May 2024 08:00:00 +0000</pubDate>") {
+		t.Errorf("RSS pubDate is not RFC 1123:\n%s", body)
+	}
+	body, _ = Encode(Atom, testFeed())
+	if !strings.Contains(string(body), "<updated>2024-05-01T09:00:00Z</updated>") {
+		t.Errorf("Atom updated is not RFC 3339:\n%s", body)
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	f := testFeed()
+	f.Items[0].Content = ""
+	body, err := Encode(JSON, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Version string `json:"version"`
+		Items   []struct {
+			ID          string `json:"id"`
+			ContentText string `json:"content_text"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != "https://jsonfeed.org/version/1.1" || len(doc.Items) != 1 {
+		t.Fatalf("unexpected feed: %s", body)
+	}
+	// content_text is required, the summary stands in for absent content
+	if doc.Items[0].ContentText != "short" {
+		t.Errorf("content_text = %q, want the summary", doc.Items[0].ContentText)
+	}
+}
@@ -0,0 +1,126 @@
+// Package i18n holds per-locale message catalogs and negotiates the
+// locale of a request from Accept-Language.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Catalog maps errcodes to messages of every loaded locale.
+type Catalog struct {
+	def      string
+	messages map[string]map[int]string // locale -> code -> message
+	locales  []string
+}
+
+var catalog = &Catalog{}
+
+// Load reads every {locale}.json under dir, e.g. en.json, zh-CN.json,
+// each a JSON object of "code": "message". def is used when negotiation
+// finds no match and must be one of the loaded locales.
+func Load(dir, def string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	c := &Catalog{def: def, messages: make(map[string]map[int]string)}
+	for _, f := range files {
+		locale := strings.TrimSuffix(filepath.Base(f), ".json")
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		var raw map[string]string
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("parse %s: %w", f, err)
+		}
+		msgs := make(map[int]string, len(raw))
+		for k, v := range raw {
+			code, err := strconv.Atoi(k)
+			if err != nil {
+				return fmt.Errorf("%s: key %q is not an errcode", f, k)
+			}
+			msgs[code] = v
+		}
+		c.messages[locale] = msgs
+		c.locales = append(c.locales, locale)
+	}
+	if _, ok := c.messages[def]; !ok {
+		return fmt.Errorf("no catalog for default locale %q in %s", def, dir)
+	}
+	sort.Strings(c.locales)
+	catalog = c
+	return nil
+}
+
+// Default returns the default locale, empty if no catalog is loaded.
+func Default() string {
+	return catalog.def
+}
+
+// Supported returns the canonical name of a loaded locale matching tag
+// case-insensitively, and whether there is one.
+func Supported(tag string) (string, bool) {
+	for _, l := range catalog.locales {
+		if strings.EqualFold(l, tag) {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// Message returns the message of code in locale, ok is false if the
+// locale or code has no entry.
+func Message(locale string, code int) (msg string, ok bool) {
+	msg, ok = catalog.messages[locale][code]
+	return
+}
+
+// Negotiate picks the loaded locale best matching an Accept-Language
+// header: the highest q-value wins, a language range matches a locale
+// exactly or by primary subtag ("en-US" matches "en", "zh" matches
+// "zh-CN"). It falls back to the default locale.
+func Negotiate(acceptLanguage string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ranges []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, r := range ranges {
+		if l, ok := Supported(r.tag); ok {
+			return l
+		}
+		primary, _, _ := strings.Cut(r.tag, "-")
+		for _, l := range catalog.locales {
+			lp, _, _ := strings.Cut(l, "-")
+			if strings.EqualFold(lp, primary) {
+				return l
+			}
+		}
+	}
+	return catalog.def
+}
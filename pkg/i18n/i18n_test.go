@@ -0,0 +1,38 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "zh-CN.json"), []byte(`{"0": "成功"}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "en.json"), []byte(`{"0": "ok"}`), 0o644)
+	if err := Load(dir, "zh-CN"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"":                          "zh-CN",
+		"en":                        "en",
+		"en-US,en;q=0.9":            "en",
+		"zh":                        "zh-CN",
+		"zh-cn":                     "zh-CN",
+		"fr-FR, en;q=0.5, zh;q=0.8": "zh-CN",
+		"fr, de":                    "zh-CN",
+		"en;q=0, zh-TW":             "zh-CN",
+	}
+	for header, want := range cases {
+		if got := Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+	if msg, _ := Message("en", 0); msg != "ok" {
+		t.Errorf("Message(en, 0) = %q", msg)
+	}
+	if _, ok := Message("fr", 0); ok {
+		t.Error("Message of unknown locale should not be found")
+	}
+}
@@ -0,0 +1,100 @@
+package imageutil
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif" // register decoders
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrTooLarge        = errors.New("image dimensions too large")
+)
+
+// MaxPixels limits decoded image size, protects against decompression bombs.
+const MaxPixels = 40 * 1000 * 1000
+
+// allowed sniffed content types
+var allowed = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Sniff detects the content type from the data itself, the client
+// provided filename and Content-Type are never trusted.
+func Sniff(data []byte) (string, error) {
+	mime := http.DetectContentType(data)
+	if !allowed[mime] {
+		return mime, ErrUnsupportedType
+	}
+	return mime, nil
+}
+
+// Decode checks type and dimensions before decoding the whole image.
+// Only pixel data survives, so re-encoding strips EXIF and other metadata.
+func Decode(data []byte) (image.Image, string, error) {
+	mime, err := Sniff(data)
+	if err != nil {
+		return nil, mime, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, mime, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, mime, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, mime, err
+	}
+	return img, mime, nil
+}
+
+// Square center-crops img to a square and scales it to size x size.
+func Square(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	crop := image.Rect(x0, y0, x0+side, y0+side)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+	return dst
+}
+
+// Fit scales img down to fit in maxW x maxH, keeping the aspect ratio.
+// Images already small enough are returned as is.
+func Fit(img image.Image, maxW, maxH int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= maxW && b.Dy() <= maxH {
+		return img
+	}
+	w, h := maxW, b.Dy()*maxW/b.Dx()
+	if h > maxH {
+		w, h = b.Dx()*maxH/b.Dy(), maxH
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// EncodePNG encodes img as PNG.
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := enc.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,54 @@
+package imageutil
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestDecodeAndSquare(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for x := 100; x < 200; x++ {
+		for y := 0; y < 100; y++ {
+			src.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	img, mime, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if mime != "image/jpeg" {
+		t.Errorf("mime = %q, want image/jpeg", mime)
+	}
+
+	sq := Square(img, 64)
+	if sq.Bounds().Dx() != 64 || sq.Bounds().Dy() != 64 {
+		t.Fatalf("Square bounds = %v, want 64x64", sq.Bounds())
+	}
+	// center crop keeps only the red middle part
+	if r, g, _, _ := sq.At(32, 32).RGBA(); r>>8 < 200 || g>>8 > 50 {
+		t.Errorf("center pixel = %v, want red", sq.At(32, 32))
+	}
+}
+
+func TestDecode_RejectsNonImage(t *testing.T) {
+	_, _, err := Decode([]byte("<svg xmlns='http://www.w3.org/2000/svg'></svg>"))
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("err = %v, want %v", err, ErrUnsupportedType)
+	}
+}
+
+func TestFit(t *testing.T) {
+	img := Fit(image.NewRGBA(image.Rect(0, 0, 1000, 500)), 200, 200)
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+		t.Errorf("Fit bounds = %v, want 200x100", b)
+	}
+}
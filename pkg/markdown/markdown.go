@@ -0,0 +1,30 @@
+// Package markdown renders article Markdown to HTML on the server.
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// md follows GitHub flavored Markdown with hard line breaks, like the
+// editor preview. Raw HTML and javascript: links are dropped, so the
+// output is safe to embed in a page.
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+// Render converts src to HTML. Fenced code blocks get a language-xxx
+// class for highlight.js.
+func Render(src string) (string, error) {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,28 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	out, err := Render("# Hi\n\n```go\nfmt.Println()\n```\n\n| a |\n|---|\n| 1 |\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<h1 id="hi">Hi</h1>`, `<code class="language-go">`, "<table>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderDropsUnsafeHTML(t *testing.T) {
+	out, err := Render("<script>alert(1)</script>\n\n[x](javascript:alert(1))\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "<script>") || strings.Contains(out, "javascript:") {
+		t.Errorf("unsafe output:\n%s", out)
+	}
+}
@@ -0,0 +1,219 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrMissingSubject = errors.New("oauth: userinfo has no subject")
+)
+
+// Config describes a single OAuth2 / OpenID Connect provider.
+// If Issuer is set and any endpoint is empty, the endpoints are
+// discovered from {Issuer}/.well-known/openid-configuration.
+type Config struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	Issuer      string
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+
+	// Claim names used to map the userinfo response, empty means default.
+	SubjectClaim  string // default "sub"
+	UsernameClaim string // default "preferred_username"
+	NicknameClaim string // default "name"
+	AvatarClaim   string // default "picture"
+	EmailClaim    string // default "email"
+}
+
+// UserInfo is the normalized identity returned by a provider.
+type UserInfo struct {
+	Subject  string
+	Username string
+	Nickname string
+	Avatar   string
+	Email    string
+}
+
+// Provider runs the authorization code flow (with PKCE) against one provider.
+type Provider struct {
+	cfg         Config
+	oauth2      *oauth2.Config
+	userInfoURL string
+	client      *http.Client
+}
+
+// NewProvider builds a Provider, running OIDC discovery if needed.
+// client may be nil, http.DefaultClient with a timeout is used then.
+func NewProvider(ctx context.Context, cfg Config, client *http.Client) (*Provider, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("oauth: provider name is empty")
+	}
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("oauth: provider %s: client id is empty", cfg.Name)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if cfg.Issuer != "" && (cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "") {
+		if err := discover(ctx, client, &cfg); err != nil {
+			return nil, fmt.Errorf("oauth: provider %s: %w", cfg.Name, err)
+		}
+	}
+	if cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "" {
+		return nil, fmt.Errorf("oauth: provider %s: auth, token and userinfo url are required", cfg.Name)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+	return &Provider{
+		cfg: cfg,
+		oauth2: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  cfg.AuthURL,
+				TokenURL: cfg.TokenURL,
+			},
+		},
+		userInfoURL: cfg.UserInfoURL,
+		client:      client,
+	}, nil
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL returns the URL to redirect the user to.
+// verifier must be kept by the caller and passed to Exchange.
+func (p *Provider) AuthCodeURL(state, verifier string) string {
+	return p.oauth2.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange trades the authorization code for a token.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*oauth2.Token, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+	return p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+}
+
+// UserInfo fetches the userinfo endpoint and maps it to UserInfo.
+func (p *Provider) UserInfo(ctx context.Context, token *oauth2.Token) (*UserInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.userInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	token.SetAuthHeader(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oauth: userinfo returned status %d", resp.StatusCode)
+	}
+
+	var claims map[string]any
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("oauth: decode userinfo: %w", err)
+	}
+	return p.mapClaims(claims)
+}
+
+func (p *Provider) mapClaims(claims map[string]any) (*UserInfo, error) {
+	info := &UserInfo{
+		Subject:  claimString(claims, p.cfg.SubjectClaim, "sub"),
+		Username: claimString(claims, p.cfg.UsernameClaim, "preferred_username"),
+		Nickname: claimString(claims, p.cfg.NicknameClaim, "name"),
+		Avatar:   claimString(claims, p.cfg.AvatarClaim, "picture"),
+		Email:    claimString(claims, p.cfg.EmailClaim, "email"),
+	}
+	if info.Subject == "" {
+		return nil, ErrMissingSubject
+	}
+	return info, nil
+}
+
+// claimString reads a claim as string, numbers (e.g. GitHub ids) are formatted.
+func claimString(claims map[string]any, name, def string) string {
+	if name == "" {
+		name = def
+	}
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
+
+func discover(ctx context.Context, client *http.Client, cfg *Config) error {
+	wellKnown := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("discovery failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("discovery returned status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&doc); err != nil {
+		return fmt.Errorf("decode discovery document: %w", err)
+	}
+	if cfg.AuthURL == "" {
+		cfg.AuthURL = doc.AuthorizationEndpoint
+	}
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = doc.TokenEndpoint
+	}
+	if cfg.UserInfoURL == "" {
+		cfg.UserInfoURL = doc.UserinfoEndpoint
+	}
+	return nil
+}
+
+// GenerateState returns a random url-safe string for the state parameter.
+func GenerateState() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// GenerateVerifier returns a new PKCE code verifier.
+func GenerateVerifier() string {
+	return oauth2.GenerateVerifier()
+}
@@ -0,0 +1,125 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// mockIdP is a minimal OIDC provider issuing a fixed code and token.
+func mockIdP(t *testing.T) *httptest.Server {
+	t.Helper()
+	var challenge string
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"userinfo_endpoint":      srv.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" {
+			t.Errorf("code_challenge_method = %q, want S256", q.Get("code_challenge_method"))
+		}
+		challenge = q.Get("code_challenge")
+		redirect, _ := url.Parse(q.Get("redirect_uri"))
+		rq := redirect.Query()
+		rq.Set("code", "mock-code")
+		rq.Set("state", q.Get("state"))
+		redirect.RawQuery = rq.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "mock-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "mock-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer mock-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":    float64(42),
+			"login": "octocat",
+			"name":  "Octo Cat",
+			"email": "octo@example.com",
+		})
+	})
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	idp := mockIdP(t)
+	ctx := context.Background()
+
+	p, err := NewProvider(ctx, Config{
+		Name:          "mock",
+		ClientID:      "client",
+		ClientSecret:  "secret",
+		RedirectURL:   "http://blog.local/callback",
+		Issuer:        idp.URL,
+		SubjectClaim:  "id",
+		UsernameClaim: "login",
+	}, idp.Client())
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+
+	state, verifier := GenerateState(), GenerateVerifier()
+	client := idp.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(p.AuthCodeURL(state, verifier))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+	if got := callback.Query().Get("state"); got != state {
+		t.Fatalf("state = %q, want %q", got, state)
+	}
+
+	if _, err := p.Exchange(ctx, callback.Query().Get("code"), "wrong-verifier"); err == nil {
+		t.Fatal("exchange with wrong verifier should fail")
+	}
+	token, err := p.Exchange(ctx, callback.Query().Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	info, err := p.UserInfo(ctx, token)
+	if err != nil {
+		t.Fatalf("UserInfo: %v", err)
+	}
+	want := UserInfo{Subject: "42", Username: "octocat", Nickname: "Octo Cat", Email: "octo@example.com"}
+	if *info != want {
+		t.Errorf("UserInfo = %+v, want %+v", *info, want)
+	}
+}
+
+func TestProvider_MissingEndpoints(t *testing.T) {
+	_, err := NewProvider(context.Background(), Config{Name: "broken", ClientID: "c"}, nil)
+	if err == nil {
+		t.Fatal("expected error for provider without endpoints")
+	}
+}
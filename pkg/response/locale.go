@@ -0,0 +1,52 @@
+package response
+
+import "net/http"
+
+// LocaleWriter carries the locale negotiated for a request down to the
+// replies of this package. Only they are localized, so only they set
+// Content-Language and Vary: Accept-Language; files, feeds and sitemaps
+// written through the same writer stay language neutral.
+type LocaleWriter struct {
+	http.ResponseWriter
+	Locale string
+}
+
+func (lw *LocaleWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
+
+// SetLocale overrides the locale negotiated for w, e.g. by the
+// preference of the user.
+func SetLocale(w http.ResponseWriter, locale string) {
+	if lw := localeWriter(w); lw != nil {
+		lw.Locale = locale
+	}
+}
+
+// localeWriter walks the Unwrap chain of w to its LocaleWriter, nil if
+// there is none.
+func localeWriter(w http.ResponseWriter) *LocaleWriter {
+	for {
+		switch rw := w.(type) {
+		case *LocaleWriter:
+			return rw
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// locale returns the locale of w and marks the reply as localized.
+func locale(w http.ResponseWriter) string {
+	lw := localeWriter(w)
+	if lw == nil {
+		return ""
+	}
+	w.Header().Add("Vary", "Accept-Language")
+	if lw.Locale != "" {
+		w.Header().Set("Content-Language", lw.Locale)
+	}
+	return lw.Locale
+}
@@ -5,14 +5,39 @@ import (
 	"net/http"
 
 	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/i18n"
 )
 
+// OnFail, if set, is called with the errcode of every failed response,
+// e.g. to count business errors.
+var OnFail func(code int)
+
+// problemJSON replies failures as RFC 7807 problem details instead of the
+// envelope, set by UseProblemJSON.
+var problemJSON bool
+
+// UseProblemJSON switches failed responses to application/problem+json.
+func UseProblemJSON(enabled bool) {
+	problemJSON = enabled
+}
+
 type responce struct {
 	Code int    `json:"code"`
 	Msg  string `json:"msg"`
 	Data any    `json:"data"`
 }
 
+// problem is an RFC 7807 problem details object, code is an extension
+// member carrying the errcode.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   int    `json:"code"`
+	Data   any    `json:"data,omitempty"`
+}
+
 func result(w http.ResponseWriter, httpStatus int, code int, data any, msg string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(httpStatus)
@@ -24,20 +49,66 @@ func result(w http.ResponseWriter, httpStatus int, code int, data any, msg strin
 	json.NewEncoder(w).Encode(resp)
 }
 
+// message returns the default msg of code in the locale of w, falling
+// back to errcode.GetMsg.
+func message(w http.ResponseWriter, code int) string {
+	if msg, ok := i18n.Message(locale(w), code); ok {
+		return msg
+	}
+	return errcode.GetMsg(code)
+}
+
 // Success returns valid result, pass a optional msg string to overwrite default msg
 func Success(w http.ResponseWriter, data any, msgs ...string) {
-	msg := errcode.GetMsg(errcode.Success)
+	msg := message(w, errcode.Success)
 	if len(msgs) > 0 && msgs[0] != "" {
 		msg = msgs[0]
 	}
 	result(w, http.StatusOK, errcode.Success, data, msg)
 }
 
-// Fail response invalid request, pass a optional msg string to overwrite default msg
+// Created replies 201 with the new resource, location is its URL.
+func Created(w http.ResponseWriter, location string, data any) {
+	w.Header().Set("Location", location)
+	result(w, http.StatusCreated, errcode.Success, data, message(w, errcode.Success))
+}
+
+// Fail response invalid request, pass a optional msg string to overwrite default msg.
+// The HTTP status follows errcode.HTTPStatus.
 func Fail(w http.ResponseWriter, code int, msgs ...string) {
-	msg := errcode.GetMsg(code)
-	if len(msgs) > 0 && msgs[0] != "" {
+	msg := ""
+	if len(msgs) > 0 {
 		msg = msgs[0]
 	}
-	result(w, http.StatusOK, code, nil, msg)
+	fail(w, code, msg, nil)
+}
+
+func fail(w http.ResponseWriter, code int, msg string, data any) {
+	if msg == "" {
+		msg = message(w, code)
+	}
+	if OnFail != nil {
+		OnFail(code)
+	}
+	status := errcode.HTTPStatus(code)
+	if problemJSON {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(status),
+			Status: status,
+			Detail: msg,
+			Code:   code,
+			Data:   data,
+		})
+		return
+	}
+	result(w, status, code, data, msg)
+}
+
+// Error replies err by its errcode.Error, any other error is a ServerError.
+func Error(w http.ResponseWriter, err error) {
+	e := errcode.From(err)
+	fail(w, e.Code, e.Msg, e.Data)
 }
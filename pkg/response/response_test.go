@@ -0,0 +1,80 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gngtwhh/WBlog/pkg/errcode"
+	"github.com/gngtwhh/WBlog/pkg/i18n"
+)
+
+func TestError(t *testing.T) {
+	errNotFound := errcode.New(errcode.ArticleNotFound, "article not found")
+	cases := []struct {
+		err        error
+		wantStatus int
+		wantCode   int
+	}{
+		{errNotFound, http.StatusNotFound, errcode.ArticleNotFound},
+		{fmt.Errorf("get article 3: %w", errNotFound), http.StatusNotFound, errcode.ArticleNotFound},
+		{errcode.New(errcode.UserExists, "dup"), http.StatusConflict, errcode.UserExists},
+		{errors.New("database is locked"), http.StatusInternalServerError, errcode.ServerError},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		Error(rec, c.err)
+		var body responce
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if rec.Code != c.wantStatus || body.Code != c.wantCode {
+			t.Errorf("Error(%v): status %d code %d, want %d %d", c.err, rec.Code, body.Code, c.wantStatus, c.wantCode)
+		}
+		if body.Msg != errcode.GetMsg(c.wantCode) {
+			t.Errorf("Error(%v): msg %q leaks the cause", c.err, body.Msg)
+		}
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	UseProblemJSON(true)
+	defer UseProblemJSON(false)
+
+	rec := httptest.NewRecorder()
+	Fail(rec, errcode.TokenInvalid)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var p problem
+	json.Unmarshal(rec.Body.Bytes(), &p)
+	if rec.Code != http.StatusUnauthorized || p.Status != http.StatusUnauthorized || p.Code != errcode.TokenInvalid || p.Title != "Unauthorized" {
+		t.Errorf("problem = %+v, status %d", p, rec.Code)
+	}
+}
+
+// TestLocaleHeaders checks only localized replies get Content-Language
+// and Vary, other writes through the same writer stay language neutral.
+func TestLocaleHeaders(t *testing.T) {
+	if err := i18n.Load("../../configs/i18n", "zh-CN"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	Fail(&LocaleWriter{ResponseWriter: rec, Locale: "en"}, errcode.NotFound)
+	var body responce
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if msg, _ := i18n.Message("en", errcode.NotFound); body.Msg != msg {
+		t.Errorf("msg = %q, want %q", body.Msg, msg)
+	}
+	if rec.Header().Get("Content-Language") != "en" || rec.Header().Get("Vary") != "Accept-Language" {
+		t.Errorf("localized reply headers = %v", rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	(&LocaleWriter{ResponseWriter: rec, Locale: "en"}).Write([]byte("body { color: red }"))
+	if rec.Header().Get("Content-Language") != "" || rec.Header().Get("Vary") != "" {
+		t.Errorf("plain write headers = %v, want none", rec.Header())
+	}
+}
@@ -0,0 +1,67 @@
+// Package sitemap encodes sitemaps and sitemap indexes of the Sitemaps
+// 0.9 protocol, https://www.sitemaps.org/protocol.html.
+package sitemap
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// MaxURLs is the most URLs one sitemap may list, larger sites split
+// their URLs into several sitemaps listed by an index.
+const MaxURLs = 50000
+
+const xmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// URL is a page, or a sitemap in an index. Loc must be absolute, a zero
+// LastMod is omitted.
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type entry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	URLs    []entry  `xml:"url"`
+}
+
+type index struct {
+	XMLName  xml.Name `xml:"sitemapindex"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Sitemaps []entry  `xml:"sitemap"`
+}
+
+func entries(urls []URL) []entry {
+	out := make([]entry, len(urls))
+	for i, u := range urls {
+		out[i].Loc = u.Loc
+		if !u.LastMod.IsZero() {
+			out[i].LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+	}
+	return out
+}
+
+// URLSet encodes a sitemap of pages.
+func URLSet(urls []URL) ([]byte, error) {
+	return encode(urlSet{Xmlns: xmlns, URLs: entries(urls)})
+}
+
+// Index encodes a sitemap index of sitemaps.
+func Index(sitemaps []URL) ([]byte, error) {
+	return encode(index{Xmlns: xmlns, Sitemaps: entries(sitemaps)})
+}
+
+func encode(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
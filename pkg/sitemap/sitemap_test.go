@@ -0,0 +1,41 @@
+package sitemap
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestURLSet(t *testing.T) {
+	body, err := URLSet([]URL{
+		{Loc: "https://blog.example.com/"},
+		{Loc: "https://blog.example.com/article/1?a=1&b=2", LastMod: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+	for _, want := range []string{
+		`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`,
+		"<loc>https://blog.example.com/</loc>",
+		"<loc>https://blog.example.com/article/1?a=1&amp;b=2</loc>",
+		"<lastmod>2024-05-01T08:00:00Z</lastmod>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("URLSet lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "<lastmod>") != 1 {
+		t.Errorf("zero LastMod must be omitted:\n%s", got)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	body, err := Index([]URL{{Loc: "https://blog.example.com/sitemap/articles-1.xml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "<sitemapindex") || !strings.Contains(string(body), "<sitemap>") {
+		t.Errorf("unexpected index:\n%s", body)
+	}
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// Memory keeps objects in memory, it is a stand-in for tests and for
+// trying remote backends locally.
+type Memory struct {
+	mu        sync.RWMutex
+	objects   map[string][]byte
+	urlPrefix string
+}
+
+func NewMemory(urlPrefix string) *Memory {
+	return &Memory{objects: make(map[string][]byte), urlPrefix: urlPrefix}
+}
+
+func (s *Memory) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.objects[key] = data
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Memory) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	data, ok := s.objects[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *Memory) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *Memory) URL(key string) string {
+	return s.urlPrefix + key
+}
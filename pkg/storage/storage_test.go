@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testStorage(t *testing.T, s Storage) {
+	ctx := context.Background()
+	if err := s.Put(ctx, "media/ab/abc.txt", strings.NewReader("hello"), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	rc, err := s.Open(ctx, "media/ab/abc.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "hello" {
+		t.Errorf("content = %q, want hello", data)
+	}
+	if got := s.URL("media/ab/abc.txt"); got != "/uploads/media/ab/abc.txt" {
+		t.Errorf("URL = %q", got)
+	}
+
+	if err := s.Delete(ctx, "media/ab/abc.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Open(ctx, "media/ab/abc.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after delete: err = %v, want %v", err, ErrNotFound)
+	}
+	for _, key := range []string{"", "/etc/passwd", "../x", "a/../../x", "a//b"} {
+		if err := s.Put(ctx, key, strings.NewReader("x"), ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q): err = %v, want %v", key, err, ErrInvalidKey)
+		}
+	}
+}
+
+func TestLocal(t *testing.T) {
+	s, err := NewLocal(t.TempDir(), "/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, s)
+}
+
+func TestMemory(t *testing.T) {
+	testStorage(t, NewMemory("/uploads/"))
+}
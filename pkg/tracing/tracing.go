@@ -0,0 +1,123 @@
+// Package tracing sets up OpenTelemetry tracing and W3C trace context
+// propagation.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/gngtwhh/WBlog"
+
+type Options struct {
+	ServiceName string
+	Version     string
+	// "otlp", "stdout", or "" to only propagate trace context
+	Exporter string
+	// OTLP/HTTP endpoint URL, e.g. http://localhost:4318/v1/traces;
+	// empty uses the OTEL_EXPORTER_OTLP_* environment variables
+	Endpoint string
+	// fraction of new traces recorded, sampled parents are always followed
+	SampleRatio float64
+	// stdout exporter output, defaults to os.Stdout
+	Writer io.Writer
+}
+
+// Setup installs the global tracer provider and propagator. The returned
+// shutdown flushes buffered spans.
+func Setup(ctx context.Context, opts *Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	switch opts.Exporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		var o []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			o = append(o, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, o...)
+	case "stdout":
+		w := opts.Writer
+		if w == nil {
+			w = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s exporter: %w", opts.Exporter, err)
+	}
+
+	ratio := opts.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(newResource(opts)),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Start starts a span of the WBlog tracer.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, opts...)
+}
+
+// End records err, if any, and ends span. Errors in ignore are expected
+// outcomes (e.g. sql.ErrNoRows) and do not mark the span failed.
+func End(span trace.Span, err error, ignore ...error) {
+	if err != nil {
+		expected := false
+		for _, e := range ignore {
+			if errors.Is(err, e) {
+				expected = true
+				break
+			}
+		}
+		if !expected {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(attribute.String("outcome", err.Error()))
+		}
+	}
+	span.End()
+}
+
+func newResource(opts *Options) *resource.Resource {
+	name := opts.ServiceName
+	if name == "" {
+		name = "wblog"
+	}
+	attrs := []attribute.KeyValue{attribute.String("service.name", name)}
+	if opts.Version != "" {
+		attrs = append(attrs, attribute.String("service.version", opts.Version))
+	}
+	// environment (OTEL_RESOURCE_ATTRIBUTES) wins over our defaults
+	res, err := resource.Merge(resource.NewSchemaless(attrs...), resource.Environment())
+	if err != nil {
+		return resource.NewSchemaless(attrs...)
+	}
+	return res
+}
@@ -0,0 +1,36 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEnd(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, ok := Start(ctx, "not-found")
+	End(ok, sql.ErrNoRows, sql.ErrNoRows)
+	_, failed := Start(ctx, "failed")
+	End(failed, errors.New("disk I/O error"), sql.ErrNoRows)
+	End(parent, nil)
+
+	spans := rec.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("ended %d spans, want 3", len(spans))
+	}
+	if spans[0].Status().Code != codes.Unset || spans[0].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected error should not fail span: %v", spans[0].Status())
+	}
+	if spans[1].Status().Code != codes.Error || len(spans[1].Events()) != 1 {
+		t.Errorf("failed span: status %v, events %d", spans[1].Status(), len(spans[1].Events()))
+	}
+}
@@ -1,50 +1,244 @@
 package utils
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"math/big"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// TODO: load jwtsecret from config/os.env
-var jwtSecret = []byte("default_jwt_secret")
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	// DefaultKeyID is used for tokens issued before kid was introduced.
+	DefaultKeyID = "default"
+)
+
+var (
+	ErrUnknownKey   = errors.New("jwt: unknown signing key")
+	ErrKeyRetired   = errors.New("jwt: signing key retired")
+	ErrUnsupportAlg = errors.New("jwt: unsupported algorithm")
+)
 
 type Claims struct {
 	UserID   uint64 `json:"user_id"`
 	Username string `json:"username"`
 	Role     int    `json:"role"`
+	Locale   string `json:"locale,omitempty"` // preferred language of the user
 	jwt.RegisteredClaims
 }
 
-func InitJwt(secret string) error {
+// SigningKey is one key of the key set, identified by ID (the "kid" header).
+type SigningKey struct {
+	ID  string
+	Alg string
+
+	secret  []byte        // HS256
+	private crypto.Signer // RS256 / EdDSA
+	public  crypto.PublicKey
+
+	// VerifyUntil is the end of the grace period of a retired key,
+	// zero means the key is not retired.
+	VerifyUntil time.Time
+}
+
+// JwtOptions configures the key set used by GenToken and ParseToken.
+type JwtOptions struct {
+	Keys      []*SigningKey
+	ActiveKey string   // ID of the key used for signing
+	Issuer    string   // set on new tokens and required when parsing
+	Audience  []string // set on new tokens, parsed tokens must contain the first one
+}
+
+type keySet struct {
+	mu       sync.RWMutex
+	keys     map[string]*SigningKey
+	active   *SigningKey
+	issuer   string
+	audience []string
+}
+
+var jwtKeys = &keySet{}
+
+// NewHMACKey creates a HS256 key.
+func NewHMACKey(id, secret string) (*SigningKey, error) {
 	if secret == "" {
-		return fmt.Errorf("secret is empty")
+		return nil, fmt.Errorf("jwt: key %s: secret is empty", id)
+	}
+	return &SigningKey{ID: id, Alg: AlgHS256, secret: []byte(secret)}, nil
+}
+
+// NewPrivateKey creates a RS256 or EdDSA key from a PEM encoded
+// PKCS#8 (or PKCS#1 for RSA) private key.
+func NewPrivateKey(id, alg string, pemData []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, fmt.Errorf("jwt: key %s: no PEM block found", id)
+	}
+	var parsed any
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwt: key %s: %w", id, err)
+	}
+
+	key := &SigningKey{ID: id, Alg: alg}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if alg != AlgRS256 {
+			return nil, fmt.Errorf("jwt: key %s: rsa key can not be used with %s", id, alg)
+		}
+		key.private, key.public = k, &k.PublicKey
+	case ed25519.PrivateKey:
+		if alg != AlgEdDSA {
+			return nil, fmt.Errorf("jwt: key %s: ed25519 key can not be used with %s", id, alg)
+		}
+		key.private, key.public = k, k.Public()
+	default:
+		return nil, fmt.Errorf("jwt: key %s: %w", id, ErrUnsupportAlg)
+	}
+	return key, nil
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	switch k.Alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodHS256
+}
+
+func (k *SigningKey) signKey() any {
+	if k.Alg == AlgHS256 {
+		return k.secret
+	}
+	return k.private
+}
+
+func (k *SigningKey) verifyKey() any {
+	if k.Alg == AlgHS256 {
+		return k.secret
 	}
-	jwtSecret = []byte(secret)
+	return k.public
+}
+
+func (k *SigningKey) retired() bool {
+	return !k.VerifyUntil.IsZero()
+}
+
+// InitJwt replaces the key set, it is safe to call again to rotate keys.
+func InitJwt(opts JwtOptions) error {
+	keys := make(map[string]*SigningKey, len(opts.Keys))
+	for _, k := range opts.Keys {
+		if k.ID == "" {
+			return errors.New("jwt: key id is empty")
+		}
+		if _, dup := keys[k.ID]; dup {
+			return fmt.Errorf("jwt: duplicated key id %s", k.ID)
+		}
+		switch k.Alg {
+		case AlgHS256, AlgRS256, AlgEdDSA:
+		default:
+			return fmt.Errorf("jwt: key %s: %w: %s", k.ID, ErrUnsupportAlg, k.Alg)
+		}
+		keys[k.ID] = k
+	}
+	active, ok := keys[opts.ActiveKey]
+	if !ok {
+		return fmt.Errorf("jwt: active key %q: %w", opts.ActiveKey, ErrUnknownKey)
+	}
+	if active.retired() {
+		return fmt.Errorf("jwt: active key %q: %w", opts.ActiveKey, ErrKeyRetired)
+	}
+
+	jwtKeys.mu.Lock()
+	defer jwtKeys.mu.Unlock()
+	jwtKeys.keys = keys
+	jwtKeys.active = active
+	jwtKeys.issuer = opts.Issuer
+	jwtKeys.audience = opts.Audience
 	return nil
 }
-func GenToken(userID uint64, username string, role int, expires time.Duration, issuer string) (string, error) {
+
+func GenToken(userID uint64, username string, role int, locale string, expires time.Duration) (string, error) {
+	jwtKeys.mu.RLock()
+	key, issuer, audience := jwtKeys.active, jwtKeys.issuer, jwtKeys.audience
+	jwtKeys.mu.RUnlock()
+	if key == nil {
+		return "", ErrUnknownKey
+	}
+
+	now := time.Now()
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
 		Role:     role,
+		Locale:   locale,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expires)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expires)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    issuer,
+			Audience:  audience,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey())
 }
 
 func ParseToken(tokenString string) (*Claims, error) {
+	jwtKeys.mu.RLock()
+	keys, issuer, audience := jwtKeys.keys, jwtKeys.issuer, jwtKeys.audience
+	jwtKeys.mu.RUnlock()
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgEdDSA}),
+		jwt.WithExpirationRequired(),
+	}
+	if issuer != "" {
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+	if len(audience) > 0 {
+		opts = append(opts, jwt.WithAudience(audience[0]))
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			kid = DefaultKeyID
+		}
+		key, ok := keys[kid]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		if key.retired() && time.Now().After(key.VerifyUntil) {
+			return nil, ErrKeyRetired
+		}
+		// a token must never choose its own algorithm
+		if token.Method.Alg() != key.Alg {
+			return nil, ErrUnsupportAlg
+		}
+		return key.verifyKey(), nil
+	}, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -53,3 +247,48 @@ func ParseToken(tokenString string) (*Claims, error) {
 	}
 	return nil, errors.New("invalid token")
 }
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public keys that can still verify tokens.
+// HS256 secrets are never published.
+func JWKS() []JWK {
+	jwtKeys.mu.RLock()
+	defer jwtKeys.mu.RUnlock()
+
+	now := time.Now()
+	list := make([]JWK, 0, len(jwtKeys.keys))
+	for _, k := range jwtKeys.keys {
+		if k.retired() && now.After(k.VerifyUntil) {
+			continue
+		}
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			list = append(list, JWK{
+				Kty: "RSA", Kid: k.ID, Alg: k.Alg, Use: "sig",
+				N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			list = append(list, JWK{
+				Kty: "OKP", Kid: k.ID, Alg: k.Alg, Use: "sig",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Kid < list[j].Kid })
+	return list
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newEdKey(t *testing.T, id string) *SigningKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := NewPrivateKey(id, AlgEdDSA, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	return k
+}
+
+func TestJwt_Rotation(t *testing.T) {
+	oldKey, _ := NewHMACKey(DefaultKeyID, "old-secret")
+	newKey := newEdKey(t, "2026-01")
+
+	opts := JwtOptions{Keys: []*SigningKey{oldKey}, ActiveKey: DefaultKeyID, Issuer: "WBLOG", Audience: []string{"wblog-web"}}
+	if err := InitJwt(opts); err != nil {
+		t.Fatalf("InitJwt: %v", err)
+	}
+	oldToken, err := GenToken(1, "alice", 1, "", time.Hour)
+	if err != nil {
+		t.Fatalf("GenToken: %v", err)
+	}
+
+	// rotate: new key signs, old key verifies during grace period
+	oldKey.VerifyUntil = time.Now().Add(time.Hour)
+	opts.Keys = []*SigningKey{oldKey, newKey}
+	opts.ActiveKey = newKey.ID
+	if err := InitJwt(opts); err != nil {
+		t.Fatalf("InitJwt: %v", err)
+	}
+	newToken, err := GenToken(2, "bob", 1, "", time.Hour)
+	if err != nil {
+		t.Fatalf("GenToken: %v", err)
+	}
+	for _, tok := range []string{oldToken, newToken} {
+		if _, err := ParseToken(tok); err != nil {
+			t.Errorf("ParseToken during grace period: %v", err)
+		}
+	}
+	if jwks := JWKS(); len(jwks) != 1 || jwks[0].Kid != newKey.ID || jwks[0].Crv != "Ed25519" {
+		t.Errorf("JWKS = %+v, want only the Ed25519 key", jwks)
+	}
+
+	// grace period over
+	oldKey.VerifyUntil = time.Now().Add(-time.Second)
+	if _, err := ParseToken(oldToken); !errors.Is(err, ErrKeyRetired) {
+		t.Errorf("ParseToken after grace period: err = %v, want %v", err, ErrKeyRetired)
+	}
+}
+
+func TestJwt_IssuerAudience(t *testing.T) {
+	key, _ := NewHMACKey(DefaultKeyID, "secret")
+	if err := InitJwt(JwtOptions{Keys: []*SigningKey{key}, ActiveKey: DefaultKeyID, Issuer: "other"}); err != nil {
+		t.Fatal(err)
+	}
+	token, _ := GenToken(1, "alice", 1, "", time.Hour)
+
+	if err := InitJwt(JwtOptions{Keys: []*SigningKey{key}, ActiveKey: DefaultKeyID, Issuer: "WBLOG"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(token); err == nil {
+		t.Error("token with wrong issuer should be rejected")
+	}
+
+	if err := InitJwt(JwtOptions{Keys: []*SigningKey{key}, ActiveKey: DefaultKeyID, Issuer: "other", Audience: []string{"api"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(token); err == nil {
+		t.Error("token without audience should be rejected")
+	}
+}
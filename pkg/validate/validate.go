@@ -0,0 +1,202 @@
+// Package validate checks structs against rules in their `validate` tags:
+//
+//	Username string `json:"username" validate:"required,min=3,max=32,pattern=^[A-Za-z0-9_]+$"`
+//	Status   string `json:"status" validate:"enum=draft|published"`
+//
+// Rules are separated by commas, pattern must come last since its regexp
+// may contain commas. min and max bound the rune count of strings, the
+// length of slices and maps, and the value of numbers; maxbytes bounds the
+// UTF-8 length of strings, for limits like bcrypt's. A nil pointer only
+// fails required, so optional PATCH fields are checked when present.
+// Fields are named by their json tag.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError is one failed rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Errors are all failed rules of a struct, in field order.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type rule struct {
+	name  string
+	param string
+	num   float64        // min, max, maxbytes
+	re    *regexp.Regexp // pattern
+	enum  []string
+}
+
+type field struct {
+	index []int
+	name  string
+	rules []rule
+}
+
+var cache sync.Map // reflect.Type -> []field
+
+// Struct validates v, a struct or a pointer to one. It returns Errors if
+// any rule fails, and panics on malformed tags like regexp.MustCompile.
+func Struct(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	var errs Errors
+	for _, f := range fields(rv.Type()) {
+		fv := rv.FieldByIndex(f.index)
+		for _, r := range f.rules {
+			if msg := r.check(fv); msg != "" {
+				errs = append(errs, FieldError{Field: f.name, Rule: r.name, Param: r.param, Message: msg})
+				break // one error per field
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func fields(t reflect.Type) []field {
+	if fs, ok := cache.Load(t); ok {
+		return fs.([]field)
+	}
+	var fs []field
+	collect(t, nil, &fs)
+	cache.Store(t, fs)
+	return fs
+}
+
+func collect(t reflect.Type, index []int, fs *[]field) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		idx := append(append([]int(nil), index...), i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			collect(sf.Type, idx, fs)
+			continue
+		}
+		tag := sf.Tag.Get("validate")
+		if tag == "" || !sf.IsExported() {
+			continue
+		}
+		if name == "" || name == "-" {
+			name = sf.Name
+		}
+		*fs = append(*fs, field{index: idx, name: name, rules: parse(t, sf.Name, tag)})
+	}
+}
+
+func parse(t reflect.Type, fieldName, tag string) []rule {
+	var rules []rule
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "pattern=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+		name, param, _ := strings.Cut(part, "=")
+		r := rule{name: name, param: param}
+		switch name {
+		case "required":
+		case "min", "max", "maxbytes":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				panic(fmt.Sprintf("validate: %s.%s: bad %s %q", t, fieldName, name, param))
+			}
+			r.num = n
+		case "pattern":
+			r.re = regexp.MustCompile(param)
+		case "enum":
+			r.enum = strings.Split(param, "|")
+		default:
+			panic(fmt.Sprintf("validate: %s.%s: unknown rule %q", t, fieldName, name))
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// check returns the message of a failed rule, empty if v passes.
+func (r rule) check(v reflect.Value) string {
+	if r.name == "required" {
+		if v.IsZero() || (v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "") {
+			return "is required"
+		}
+		return ""
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch r.name {
+	case "min", "max":
+		n, unit := size(v)
+		if r.name == "min" && n < r.num {
+			return fmt.Sprintf("must be at least %s%s", r.param, unit)
+		}
+		if r.name == "max" && n > r.num {
+			return fmt.Sprintf("must be at most %s%s", r.param, unit)
+		}
+	case "maxbytes":
+		if v.Kind() == reflect.String && float64(len(v.String())) > r.num {
+			return fmt.Sprintf("must be at most %s bytes", r.param)
+		}
+	case "pattern":
+		if v.Kind() == reflect.String && v.String() != "" && !r.re.MatchString(v.String()) {
+			return "has an invalid format"
+		}
+	case "enum":
+		if v.IsZero() {
+			return "" // absent, only required rejects it
+		}
+		s := fmt.Sprint(v.Interface())
+		for _, e := range r.enum {
+			if s == e {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(r.enum, ", ")
+	}
+	return ""
+}
+
+// size returns what min and max compare, and its unit for messages.
+func size(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	}
+	return 0, ""
+}
@@ -0,0 +1,56 @@
+package validate
+
+import (
+	"errors"
+	"testing"
+)
+
+type embedded struct {
+	Title string `json:"title" validate:"required,max=5"`
+}
+
+type request struct {
+	ID uint64 `json:"id" validate:"required"`
+	embedded
+	Name   string  `json:"name" validate:"min=2,pattern=^[a-z,]+$"`
+	Status string  `json:"status" validate:"enum=draft|published"`
+	Patch  *string `json:"patch" validate:"min=1"`
+	Secret string  `json:"secret" validate:"max=4,maxbytes=4"`
+	Tags   []string
+}
+
+func TestStruct(t *testing.T) {
+	empty, long := "", "abc"
+	cases := []struct {
+		req  request
+		want []string // field:rule
+	}{
+		{request{ID: 1, embedded: embedded{"hi"}, Name: "a,b"}, nil},
+		{request{ID: 1, embedded: embedded{"hi"}, Patch: &long}, []string{"name:min"}},
+		{request{embedded: embedded{"   "}, Name: "ab"}, []string{"id:required", "title:required"}},
+		{request{ID: 1, embedded: embedded{"héllo!"}, Name: "AB"}, []string{"title:max", "name:pattern"}},
+		{request{ID: 1, embedded: embedded{"hi"}, Name: "ab", Status: "gone", Patch: &empty}, []string{"status:enum", "patch:min"}},
+		{request{ID: 1, embedded: embedded{"hi"}, Name: "ab", Secret: "密码"}, []string{"secret:maxbytes"}},
+	}
+	for i, c := range cases {
+		err := Struct(&c.req)
+		var errs Errors
+		if err != nil && !errors.As(err, &errs) {
+			t.Fatalf("case %d: err %T is not Errors", i, err)
+		}
+		var got []string
+		for _, fe := range errs {
+			got = append(got, fe.Field+":"+fe.Rule)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("case %d: got %v, want %v", i, got, c.want)
+			continue
+		}
+		for j := range got {
+			if got[j] != c.want[j] {
+				t.Errorf("case %d: got %v, want %v", i, got, c.want)
+				break
+			}
+		}
+	}
+}